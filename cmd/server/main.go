@@ -29,7 +29,7 @@ func main() {
 
 	env := config.LoadConfig()
 
-	spreadsheet := spreadsheet.New(ctxSignal, env.ServiceCredentialsPath, env.SpreadsheetID)
+	spreadsheet := spreadsheet.New(ctxSignal, env)
 
 	server := launchServer(env, spreadsheet)
 
@@ -60,12 +60,58 @@ func launchServer(env *config.Config, spreadsheet *spreadsheet.Spreadsheet) *htt
 
 	router.Get("/api/v1/areas", wallsHandlers.ReadAreasTo)
 	router.Post("/api/v1/areas/upload", wallsHandlers.UploadAreasFrom)
+	router.Get("/api/v1/areas/{name}", wallsHandlers.ReadAreaTo)
+	router.Put("/api/v1/areas/{name}", wallsHandlers.WriteAreaFrom)
+	router.Patch("/api/v1/areas/{name}", wallsHandlers.PatchAreaFrom)
+	router.Delete("/api/v1/areas/{name}", wallsHandlers.DeleteArea)
 
 	router.Get("/api/v1/materials", wallsHandlers.ReadMaterialsTo)
 	router.Post("/api/v1/materials/upload", wallsHandlers.UploadMaterialsFrom)
+	router.Get("/api/v1/materials/validate", wallsHandlers.ReadInvalidColorsTo)
+	router.Get("/api/v1/materials/{name}", wallsHandlers.ReadMaterialTo)
+	router.Put("/api/v1/materials/{name}", wallsHandlers.WriteMaterialFrom)
+	router.Patch("/api/v1/materials/{name}", wallsHandlers.PatchMaterialFrom)
+	router.Delete("/api/v1/materials/{name}", wallsHandlers.DeleteMaterial)
 
 	router.Get("/api/v1/areas_relations", wallsHandlers.ReadAreasRelationsTo)
 	router.Post("/api/v1/areas_relations/upload", wallsHandlers.UploadAreasRelationsFrom)
+	router.Get("/api/v1/areas_relations/analysis", wallsHandlers.ReadAreasRelationsAnalysisTo)
+	router.Post("/api/v1/areas_relations/analysis", wallsHandlers.ReadAreasRelationsAnalysisTo)
+	router.Get("/api/v1/areas_relations/graph", wallsHandlers.ReadAreasRelationsGraphTo)
+	router.Get("/api/v1/areas_relations/matrix", wallsHandlers.ReadAreasRelationsMatrixTo)
+	router.Post("/api/v1/areas_relations/matrix/upload", wallsHandlers.UploadAreasRelationsMatrixFrom)
+	router.Get("/api/v1/areas_relations/{internal}/{external}", wallsHandlers.ReadAreaRelationTo)
+	router.Put("/api/v1/areas_relations/{internal}/{external}", wallsHandlers.WriteAreaRelationFrom)
+	router.Patch("/api/v1/areas_relations/{internal}/{external}", wallsHandlers.PatchAreaRelationFrom)
+	router.Delete("/api/v1/areas_relations/{internal}/{external}", wallsHandlers.DeleteAreaRelation)
+
+	// /wall_types keeps serving one assembly per relation as it always did,
+	// the deduplicated types have their own path.
+	router.Get("/api/v1/wall_types", wallsHandlers.ReadWallAssembliesTo)
+	router.Get("/api/v1/wall_types/deduplicated", wallsHandlers.ReadWallTypesTo)
+	router.Post("/api/v1/wall_types/write", wallsHandlers.WriteWallTypes)
+	router.Get("/api/v1/wall_types/violations", wallsHandlers.ReadWallTypesViolationsTo)
+	router.Get("/api/v1/wall_types/thermal", wallsHandlers.ReadWallTypesThermalTo)
+	router.Get("/api/v1/wall_types/carbon", wallsHandlers.ReadWallTypesCarbonTo)
+	router.Get("/api/v1/wall_types/ratings", wallsHandlers.ReadWallTypesRatingsTo)
+	router.Get("/api/v1/wall_types.ifc", wallsHandlers.ReadWallTypesIFCTo)
+	router.Get("/api/v1/wall_types.gbxml", wallsHandlers.ReadWallTypesGBXMLTo)
+	router.Get("/api/v1/wall_types/sections.svg", wallsHandlers.ReadWallTypesSectionsTo)
+	router.Get("/api/v1/wall_types/sections.html", wallsHandlers.ReadWallTypesSectionsHTMLTo)
+	router.Get("/api/v1/wall_types/sections.dxf", wallsHandlers.ReadWallTypesDXFTo)
+	router.Get("/api/v1/wall_types/{name}/section.svg", wallsHandlers.ReadWallTypeSectionTo)
+	router.Get("/api/v1/wall_types/{name}/section.dxf", wallsHandlers.ReadWallTypeDXFTo)
+
+	router.Post("/api/v1/quantities", wallsHandlers.ReadQuantitiesTo)
+	router.Post("/api/v1/costs", wallsHandlers.ReadCostsTo)
+
+	router.Get("/api/v1/keynotes.txt", wallsHandlers.ReadKeynotesTo)
+	router.Post("/api/v1/keynotes/validate", wallsHandlers.ValidateKeynotesFrom)
+
+	router.Get("/api/v1/patterns", wallsHandlers.ReadPatternsTo)
+	router.Get("/api/v1/patterns/validate", wallsHandlers.ReadMissingPatternsTo)
+	router.Get("/api/v1/patterns.pat", wallsHandlers.ReadPatFileTo)
+	router.Post("/api/v1/patterns/upload", wallsHandlers.UploadPatFileFrom)
 
 	server := &http.Server{
 		Addr:    env.ServerAddress,
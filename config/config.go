@@ -2,8 +2,11 @@ package config
 
 import (
 	"log"
+	"strconv"
+	"strings"
 
 	"github.com/caarlos0/env/v6"
+	"github.com/pkg/errors"
 )
 
 const (
@@ -15,6 +18,38 @@ type Config struct {
 	ServiceCredentialsPath string `env:"SERVICE_CREDENTIALS_PATH,required"`
 
 	ServerAddress string `env:"SERVER_ADDRESS,required"`
+
+	WallTypeNameTemplate string  `env:"WALL_TYPE_NAME_TEMPLATE" envDefault:"{totalThickness}mm {coreMaterial} {keynote}"`
+	ThermalTargetUValue  float64 `env:"THERMAL_TARGET_U_VALUE" envDefault:"0.28"`
+
+	// CurrencyRates holds CODE:RATE pairs, every rate being the value of one
+	// unit of the currency in a common reference currency.
+	CurrencyRates []string `env:"CURRENCY_RATES" envSeparator:"," envDefault:"EUR:1"`
+	CostCurrency  string   `env:"COST_CURRENCY" envDefault:"EUR"`
+
+	// ThicknessUnit is the unit of the MATERIALS thickness column when its
+	// header doesn't declare one, e.g. "Thickness (in)".
+	ThicknessUnit string `env:"THICKNESS_UNIT" envDefault:"mm"`
+}
+
+func (c *Config) Rates() (map[string]float64, error) {
+	rates := map[string]float64{}
+
+	for _, pair := range c.CurrencyRates {
+		code, value, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errors.Errorf("invalid currency rate %q, expected CODE:RATE", pair)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate <= 0 {
+			return nil, errors.Errorf("invalid currency rate %q, expected a positive number", pair)
+		}
+
+		rates[strings.ToUpper(strings.TrimSpace(code))] = rate
+	}
+
+	return rates, nil
 }
 
 func showConfig(cfg *Config) {
@@ -22,6 +57,11 @@ func showConfig(cfg *Config) {
 	log.Printf("SPREADSHEET_ID\t\t= %s", cfg.SpreadsheetID)
 	log.Printf("SERVICE_CREDENTIALS_PATH\t= %s", cfg.ServiceCredentialsPath)
 	log.Printf("SERVER_ADDRESS\t\t= %s", cfg.ServerAddress)
+	log.Printf("WALL_TYPE_NAME_TEMPLATE\t= %s", cfg.WallTypeNameTemplate)
+	log.Printf("THERMAL_TARGET_U_VALUE\t= %g", cfg.ThermalTargetUValue)
+	log.Printf("CURRENCY_RATES\t\t= %s", strings.Join(cfg.CurrencyRates, ","))
+	log.Printf("COST_CURRENCY\t\t= %s", cfg.CostCurrency)
+	log.Printf("THICKNESS_UNIT\t\t= %s", cfg.ThicknessUnit)
 }
 
 func LoadConfig() *Config {
@@ -31,6 +71,10 @@ func LoadConfig() *Config {
 		panic(err)
 	}
 
+	if _, err := cfg.Rates(); err != nil {
+		panic(err)
+	}
+
 	showConfig(cfg)
 
 	return cfg
@@ -1,25 +1,81 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/pkg/errors"
+
+	"arca3/models"
 )
 
+const unitsHeader = "X-Units"
+
 type Spreadsheet interface {
 	ResetData()
 
-	ReadAreasMaterialsTo(ctx context.Context, dst io.Writer) error
+	ReadAreasMaterialsTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error
+
+	ReadAreasRelationsTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error
+	UploadAreasRelationsFrom(ctx context.Context, src io.Reader, format models.Format) error
+	ReadAreasRelationsAnalysisTo(ctx context.Context, src io.Reader, dst io.Writer) error
+	ReadAreasRelationsGraphTo(ctx context.Context, dst io.Writer, format models.GraphFormat, filter models.AreaFilter) error
+	ReadAreasRelationsMatrixTo(ctx context.Context, dst io.Writer, format models.Format) error
+	UploadAreasRelationsMatrixFrom(ctx context.Context, src io.Reader, format models.Format) error
+
+	ReadAreasTo(ctx context.Context, dst io.Writer, filter models.AreaFilter, options models.ReadOptions) error
+	UploadAreasFrom(ctx context.Context, src io.Reader, format models.Format) error
+
+	ReadMaterialsTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error
+	UploadMaterialsFrom(ctx context.Context, src io.Reader, format models.Format) error
+	ReadInvalidColorsTo(ctx context.Context, dst io.Writer) error
+
+	ReadWallTypesTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error
+	ReadWallAssembliesTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error
+	WriteWallTypes(ctx context.Context) error
+	ReadWallTypesViolationsTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypesThermalTo(ctx context.Context, dst io.Writer, targetU *float64) error
+	ReadWallTypesCarbonTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypesRatingsTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypesIFCTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypesGBXMLTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypeSectionTo(ctx context.Context, name string, dst io.Writer) error
+	ReadWallTypesSectionsTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypesSectionsHTMLTo(ctx context.Context, dst io.Writer) error
+	ReadWallTypeDXFTo(ctx context.Context, name string, dst io.Writer) error
+	ReadWallTypesDXFTo(ctx context.Context, dst io.Writer) error
+
+	ReadQuantitiesTo(ctx context.Context, src io.Reader, dst io.Writer, format models.Format) error
+	ReadCostsTo(ctx context.Context, src io.Reader, dst io.Writer, currency string) error
 
-	ReadAreasRelationsTo(ctx context.Context, dst io.Writer) error
-	UploadAreasRelationsFrom(ctx context.Context, dst io.Reader) error
+	ReadKeynotesTo(ctx context.Context, dst io.Writer) error
+	ValidateKeynotesFrom(ctx context.Context, src io.Reader, dst io.Writer) error
 
-	ReadAreasTo(ctx context.Context, dst io.Writer) error
-	UploadAreasFrom(ctx context.Context, dst io.Reader) error
+	ReadPatternsTo(ctx context.Context, dst io.Writer) error
+	ReadMissingPatternsTo(ctx context.Context, dst io.Writer) error
+	ReadPatFileTo(ctx context.Context, dst io.Writer) error
+	UploadPatFileFrom(ctx context.Context, src io.Reader) error
 
-	ReadMaterialsTo(ctx context.Context, dst io.Writer) error
-	UploadMaterialsFrom(ctx context.Context, src io.Reader) error
+	ReadMaterialTo(ctx context.Context, name string, dst io.Writer, options models.ReadOptions) error
+	WriteMaterialFrom(ctx context.Context, name string, src io.Reader, dst io.Writer, units models.LengthUnit) (bool, error)
+	PatchMaterialFrom(ctx context.Context, name string, src io.Reader, dst io.Writer, units models.LengthUnit) error
+	DeleteMaterial(ctx context.Context, name string) error
+
+	ReadAreaTo(ctx context.Context, name string, dst io.Writer, options models.ReadOptions) error
+	WriteAreaFrom(ctx context.Context, name string, src io.Reader, dst io.Writer) (bool, error)
+	PatchAreaFrom(ctx context.Context, name string, src io.Reader, dst io.Writer) error
+	DeleteArea(ctx context.Context, name string) error
+
+	ReadAreaRelationTo(ctx context.Context, internal, external string, dst io.Writer, options models.ReadOptions) error
+	WriteAreaRelationFrom(ctx context.Context, internal, external string, src io.Reader, dst io.Writer, units models.LengthUnit) (bool, error)
+	PatchAreaRelationFrom(ctx context.Context, internal, external string, src io.Reader, dst io.Writer, units models.LengthUnit) error
+	DeleteAreaRelation(ctx context.Context, internal, external string) error
 }
 
 type WallsHandler struct {
@@ -37,7 +93,16 @@ func (h *WallsHandler) ResetData(writer http.ResponseWriter, request *http.Reque
 }
 
 func (h *WallsHandler) ReadAreasMaterialsTo(writer http.ResponseWriter, request *http.Request) {
-	if err := h.spreadsheet.ReadAreasMaterialsTo(request.Context(), writer); err != nil {
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setReadHeaders(writer, options, "areas_materials")
+
+	if err := h.spreadsheet.ReadAreasMaterialsTo(request.Context(), writer, options); err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 
 		return
@@ -45,7 +110,16 @@ func (h *WallsHandler) ReadAreasMaterialsTo(writer http.ResponseWriter, request
 }
 
 func (h *WallsHandler) ReadAreasRelationsTo(writer http.ResponseWriter, request *http.Request) {
-	if err := h.spreadsheet.ReadAreasRelationsTo(request.Context(), writer); err != nil {
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setReadHeaders(writer, options, "areas_relations")
+
+	if err := h.spreadsheet.ReadAreasRelationsTo(request.Context(), writer, options); err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 
 		return
@@ -55,16 +129,109 @@ func (h *WallsHandler) ReadAreasRelationsTo(writer http.ResponseWriter, request
 func (h *WallsHandler) UploadAreasRelationsFrom(writer http.ResponseWriter, request *http.Request) {
 	defer request.Body.Close()
 
-	if err := h.spreadsheet.UploadAreasRelationsFrom(request.Context(), request.Body); err != nil {
+	format, err := uploadFormatFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.UploadAreasRelationsFrom(request.Context(), request.Body, format); err != nil {
 		log.Printf("Error uploading areas relations: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadAreasRelationsAnalysisTo(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	if err := h.spreadsheet.ReadAreasRelationsAnalysisTo(request.Context(), request.Body, writer); err != nil {
+		log.Printf("Error analyzing areas relations: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadAreasRelationsGraphTo(writer http.ResponseWriter, request *http.Request) {
+	format, err := models.ParseGraphFormat(request.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	filter, err := areaFilterFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", format.ContentType())
+
+	if err := h.spreadsheet.ReadAreasRelationsGraphTo(request.Context(), writer, format, filter); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadAreasRelationsMatrixTo(writer http.ResponseWriter, request *http.Request) {
+	format, err := models.ParseFormat(request.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setDownloadHeaders(writer, format, "areas_relations_matrix")
+
+	if err := h.spreadsheet.ReadAreasRelationsMatrixTo(request.Context(), writer, format); err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 
 		return
 	}
 }
 
+func (h *WallsHandler) UploadAreasRelationsMatrixFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	format, err := uploadFormatFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.UploadAreasRelationsMatrixFrom(request.Context(), request.Body, format); err != nil {
+		log.Printf("Error uploading areas relations matrix: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
 func (h *WallsHandler) ReadAreasTo(writer http.ResponseWriter, request *http.Request) {
-	if err := h.spreadsheet.ReadAreasTo(request.Context(), writer); err != nil {
+	filter, err := areaFilterFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setReadHeaders(writer, options, "areas")
+
+	if err := h.spreadsheet.ReadAreasTo(request.Context(), writer, filter, options); err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 
 		return
@@ -74,16 +241,40 @@ func (h *WallsHandler) ReadAreasTo(writer http.ResponseWriter, request *http.Req
 func (h *WallsHandler) UploadAreasFrom(writer http.ResponseWriter, request *http.Request) {
 	defer request.Body.Close()
 
-	if err := h.spreadsheet.UploadAreasFrom(request.Context(), request.Body); err != nil {
+	format, err := uploadFormatFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.UploadAreasFrom(request.Context(), request.Body, format); err != nil {
 		log.Printf("Error uploading areas: %v", err)
-		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		http.Error(writer, err.Error(), statusFromError(err))
 
 		return
 	}
 }
 
 func (h *WallsHandler) ReadMaterialsTo(writer http.ResponseWriter, request *http.Request) {
-	if err := h.spreadsheet.ReadMaterialsTo(request.Context(), writer); err != nil {
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setReadHeaders(writer, options, "materials")
+
+	if err := h.spreadsheet.ReadMaterialsTo(request.Context(), writer, options); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadInvalidColorsTo(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.ReadInvalidColorsTo(request.Context(), writer); err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 
 		return
@@ -93,10 +284,708 @@ func (h *WallsHandler) ReadMaterialsTo(writer http.ResponseWriter, request *http
 func (h *WallsHandler) UploadMaterialsFrom(writer http.ResponseWriter, request *http.Request) {
 	defer request.Body.Close()
 
-	if err := h.spreadsheet.UploadMaterialsFrom(request.Context(), request.Body); err != nil {
+	format, err := uploadFormatFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.UploadMaterialsFrom(request.Context(), request.Body, format); err != nil {
 		log.Printf("Error uploading materials: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesTo(writer http.ResponseWriter, request *http.Request) {
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setReadHeaders(writer, options, "wall_types")
+
+	if err := h.spreadsheet.ReadWallTypesTo(request.Context(), writer, options); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallAssembliesTo(writer http.ResponseWriter, request *http.Request) {
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setReadHeaders(writer, options, "wall_types")
+
+	if err := h.spreadsheet.ReadWallAssembliesTo(request.Context(), writer, options); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) WriteWallTypes(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.WriteWallTypes(request.Context()); err != nil {
+		log.Printf("Error writing wall types: %v", err)
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesViolationsTo(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.ReadWallTypesViolationsTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesThermalTo(writer http.ResponseWriter, request *http.Request) {
+	var targetU *float64
+
+	if value := request.URL.Query().Get("target_u"); value != "" {
+		target, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			http.Error(writer, "invalid target_u: "+err.Error(), http.StatusBadRequest)
+
+			return
+		}
+
+		targetU = &target
+	}
+
+	if err := h.spreadsheet.ReadWallTypesThermalTo(request.Context(), writer, targetU); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesCarbonTo(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.ReadWallTypesCarbonTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesRatingsTo(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.ReadWallTypesRatingsTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesIFCTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/x-step")
+	writer.Header().Set("Content-Disposition", `attachment; filename="wall_types.ifc"`)
+
+	if err := h.spreadsheet.ReadWallTypesIFCTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesGBXMLTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "application/xml")
+	writer.Header().Set("Content-Disposition", `attachment; filename="wall_types.xml"`)
+
+	if err := h.spreadsheet.ReadWallTypesGBXMLTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypeSectionTo(writer http.ResponseWriter, request *http.Request) {
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "image/svg+xml")
+
+	if err := h.spreadsheet.ReadWallTypeSectionTo(request.Context(), name, writer); err != nil {
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesSectionsTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "image/svg+xml")
+
+	if err := h.spreadsheet.ReadWallTypesSectionsTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesSectionsHTMLTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if err := h.spreadsheet.ReadWallTypesSectionsHTMLTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypeDXFTo(writer http.ResponseWriter, request *http.Request) {
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	writer.Header().Set("Content-Type", "image/vnd.dxf")
+	writer.Header().Set("Content-Disposition", `attachment; filename="section.dxf"`)
+
+	if err := h.spreadsheet.ReadWallTypeDXFTo(request.Context(), name, writer); err != nil {
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadWallTypesDXFTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "image/vnd.dxf")
+	writer.Header().Set("Content-Disposition", `attachment; filename="sections.dxf"`)
+
+	if err := h.spreadsheet.ReadWallTypesDXFTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadQuantitiesTo(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	format, err := models.ParseFormat(request.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	setDownloadHeaders(writer, format, "quantities")
+
+	if err := h.spreadsheet.ReadQuantitiesTo(request.Context(), request.Body, writer, format); err != nil {
+		log.Printf("Error computing quantities: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadCostsTo(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	if err := h.spreadsheet.ReadCostsTo(request.Context(), request.Body, writer, request.URL.Query().Get("currency")); err != nil {
+		log.Printf("Error computing costs: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadKeynotesTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+	if err := h.spreadsheet.ReadKeynotesTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ValidateKeynotesFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	if err := h.spreadsheet.ValidateKeynotesFrom(request.Context(), request.Body, writer); err != nil {
+		log.Printf("Error validating keynotes: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadPatternsTo(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.ReadPatternsTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadMissingPatternsTo(writer http.ResponseWriter, request *http.Request) {
+	if err := h.spreadsheet.ReadMissingPatternsTo(request.Context(), writer); err != nil {
 		http.Error(writer, err.Error(), http.StatusInternalServerError)
 
 		return
 	}
 }
+
+func (h *WallsHandler) ReadPatFileTo(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Header().Set("Content-Disposition", `attachment; filename="patterns.pat"`)
+
+	if err := h.spreadsheet.ReadPatFileTo(request.Context(), writer); err != nil {
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
+func (h *WallsHandler) UploadPatFileFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	if err := h.spreadsheet.UploadPatFileFrom(request.Context(), request.Body); err != nil {
+		log.Printf("Error uploading patterns: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+}
+
+func (h *WallsHandler) ReadMaterialTo(writer http.ResponseWriter, request *http.Request) {
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := h.spreadsheet.ReadMaterialTo(request.Context(), name, &buffer, options); err != nil {
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	setReadHeaders(writer, options, "material")
+	_, _ = buffer.WriteTo(writer)
+}
+
+func (h *WallsHandler) WriteMaterialFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	created, err := h.spreadsheet.WriteMaterialFrom(request.Context(), name, request.Body, &buffer, options.Units)
+	if err != nil {
+		log.Printf("Error writing material: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writeRecord(writer, created, &buffer, options.Units)
+}
+
+func (h *WallsHandler) PatchMaterialFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := h.spreadsheet.PatchMaterialFrom(request.Context(), name, request.Body, &buffer, options.Units); err != nil {
+		log.Printf("Error patching material: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writeRecord(writer, false, &buffer, options.Units)
+}
+
+func (h *WallsHandler) DeleteMaterial(writer http.ResponseWriter, request *http.Request) {
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.DeleteMaterial(request.Context(), name); err != nil {
+		log.Printf("Error deleting material: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WallsHandler) ReadAreaTo(writer http.ResponseWriter, request *http.Request) {
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := h.spreadsheet.ReadAreaTo(request.Context(), name, &buffer, options); err != nil {
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	setReadHeaders(writer, options, "area")
+	_, _ = buffer.WriteTo(writer)
+}
+
+func (h *WallsHandler) WriteAreaFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	created, err := h.spreadsheet.WriteAreaFrom(request.Context(), name, request.Body, &buffer)
+	if err != nil {
+		log.Printf("Error writing area: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writeRecord(writer, created, &buffer, "")
+}
+
+func (h *WallsHandler) PatchAreaFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := h.spreadsheet.PatchAreaFrom(request.Context(), name, request.Body, &buffer); err != nil {
+		log.Printf("Error patching area: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writeRecord(writer, false, &buffer, "")
+}
+
+func (h *WallsHandler) DeleteArea(writer http.ResponseWriter, request *http.Request) {
+	name, err := urlParam(request, "name")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.DeleteArea(request.Context(), name); err != nil {
+		log.Printf("Error deleting area: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WallsHandler) ReadAreaRelationTo(writer http.ResponseWriter, request *http.Request) {
+	internal, external, err := relationFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := h.spreadsheet.ReadAreaRelationTo(request.Context(), internal, external, &buffer, options); err != nil {
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	setReadHeaders(writer, options, "area_relation")
+	_, _ = buffer.WriteTo(writer)
+}
+
+func (h *WallsHandler) WriteAreaRelationFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	internal, external, err := relationFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	created, err := h.spreadsheet.WriteAreaRelationFrom(request.Context(), internal, external, request.Body, &buffer, options.Units)
+	if err != nil {
+		log.Printf("Error writing area relation: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writeRecord(writer, created, &buffer, options.Units)
+}
+
+func (h *WallsHandler) PatchAreaRelationFrom(writer http.ResponseWriter, request *http.Request) {
+	defer request.Body.Close()
+
+	internal, external, err := relationFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	options, err := readOptionsFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	var buffer bytes.Buffer
+
+	if err := h.spreadsheet.PatchAreaRelationFrom(request.Context(), internal, external, request.Body, &buffer, options.Units); err != nil {
+		log.Printf("Error patching area relation: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writeRecord(writer, false, &buffer, options.Units)
+}
+
+func (h *WallsHandler) DeleteAreaRelation(writer http.ResponseWriter, request *http.Request) {
+	internal, external, err := relationFromRequest(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := h.spreadsheet.DeleteAreaRelation(request.Context(), internal, external); err != nil {
+		log.Printf("Error deleting area relation: %v", err)
+		http.Error(writer, err.Error(), statusFromError(err))
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// setDownloadHeaders sets the content type of format and, for the table
+// formats, suggests a file name so browsers download the response.
+func setDownloadHeaders(writer http.ResponseWriter, format models.Format, name string) {
+	writer.Header().Set("Content-Type", format.ContentType())
+
+	if format != models.FormatJSON {
+		writer.Header().Set("Content-Disposition", `attachment; filename="`+name+"."+string(format)+`"`)
+	}
+}
+
+func statusFromError(err error) int {
+	switch {
+	case errors.Is(err, models.ErrInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// readOptionsFromRequest takes the output units from the units query
+// parameter or the X-Units header, "revit" gives the decimal feet Revit uses
+// internally. The format query parameter wins over the Accept header.
+func readOptionsFromRequest(request *http.Request) (models.ReadOptions, error) {
+	query := request.URL.Query()
+
+	value := query.Get("units")
+	if value == "" {
+		value = request.Header.Get(unitsHeader)
+	}
+
+	units, err := models.ParseLengthUnit(value)
+	if err != nil {
+		return models.ReadOptions{}, err
+	}
+
+	format := models.FormatFromAccept(request.Header.Get("Accept"))
+	if value := query.Get("format"); value != "" {
+		if format, err = models.ParseFormat(value); err != nil {
+			return models.ReadOptions{}, err
+		}
+	}
+
+	return models.ReadOptions{Units: units, Format: format}, nil
+}
+
+// uploadFormatFromRequest takes the format of a body from the format query
+// parameter or its Content-Type.
+func uploadFormatFromRequest(request *http.Request) (models.Format, error) {
+	if value := request.URL.Query().Get("format"); value != "" {
+		return models.ParseFormat(value)
+	}
+
+	return models.FormatFromContentType(request.Header.Get("Content-Type")), nil
+}
+
+// relationFromRequest reads the areas of a relation from its URL, "-" standing
+// for a relation without external area.
+func relationFromRequest(request *http.Request) (string, string, error) {
+	internal, err := urlParam(request, "internal")
+	if err != nil {
+		return "", "", err
+	}
+
+	external, err := urlParam(request, "external")
+	if err != nil {
+		return "", "", err
+	}
+
+	return internal, external, nil
+}
+
+// urlParam reads a path parameter. chi matches on the escaped path when the
+// request has one, only then is the parameter still escaped.
+func urlParam(request *http.Request, key string) (string, error) {
+	value := chi.URLParam(request, key)
+	if request.URL.RawPath == "" {
+		return value, nil
+	}
+
+	return url.PathUnescape(value)
+}
+
+// writeRecord sends the record written by a PUT or a PATCH, units being the
+// unit of its thicknesses when it has any.
+func writeRecord(writer http.ResponseWriter, created bool, record *bytes.Buffer, units models.LengthUnit) {
+	writer.Header().Set("Content-Type", models.FormatJSON.ContentType())
+
+	if units != "" {
+		writer.Header().Set(unitsHeader, string(units))
+	}
+
+	if created {
+		writer.WriteHeader(http.StatusCreated)
+	}
+
+	_, _ = record.WriteTo(writer)
+}
+
+func setReadHeaders(writer http.ResponseWriter, options models.ReadOptions, name string) {
+	setDownloadHeaders(writer, options.Format, name)
+	writer.Header().Set(unitsHeader, string(options.Units))
+	writer.Header().Add("Vary", "Accept")
+}
+
+func areaFilterFromRequest(request *http.Request) (models.AreaFilter, error) {
+	query := request.URL.Query()
+
+	filter := models.AreaFilter{
+		Level:    query.Get("level"),
+		Building: query.Get("building"),
+		UseClass: query.Get("use_class"),
+	}
+
+	if value := query.Get("type"); value != "" {
+		areaType, err := models.ParseAreaType(value)
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Type = areaType
+	}
+
+	return filter, nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type AreaType string
+
+const (
+	AreaInterior AreaType = "interior"
+	AreaExterior AreaType = "exterior"
+	AreaGround   AreaType = "ground"
+)
+
+// ParseAreaType defaults to interior, which is what every area was before
+// the AREAS tab had a type column.
+func ParseAreaType(value string) (AreaType, error) {
+	switch areaType := AreaType(strings.ToLower(strings.TrimSpace(value))); areaType {
+	case "":
+		return AreaInterior, nil
+	case AreaInterior, AreaExterior, AreaGround:
+		return areaType, nil
+	default:
+		return "", errors.Wrapf(ErrInvalid, "unknown area type %q", value)
+	}
+}
+
+// IsExterior tells whether the area is outside the building envelope.
+func (a *Area) IsExterior() bool {
+	return a.Type == AreaExterior || a.Type == AreaGround
+}
+
+// AreaFilter selects areas by attribute, empty fields match everything.
+type AreaFilter struct {
+	Type     AreaType
+	Level    string
+	Building string
+	UseClass string
+}
+
+func (f AreaFilter) Matches(area *Area) bool {
+	if area == nil {
+		return false
+	}
+
+	if f.Type != "" && area.Type != f.Type {
+		return false
+	}
+
+	return matchesOptional(f.Level, area.Level) &&
+		matchesOptional(f.Building, area.Building) &&
+		matchesOptional(f.UseClass, area.UseClass)
+}
+
+func matchesOptional(expected string, value *string) bool {
+	if expected == "" {
+		return true
+	}
+
+	return value != nil && *value == expected
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Color is an RGB color, the pattern colors of a Revit material.
+type Color struct {
+	R uint8
+	G uint8
+	B uint8
+}
+
+var (
+	colorHex     = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+	colorTriplet = regexp.MustCompile(`^(?i:rgb)?\s*\(?\s*(\d{1,3})\s*[,;\s-]\s*(\d{1,3})\s*[,;\s-]\s*(\d{1,3})\s*\)?$`)
+)
+
+// colorPalette holds the names accepted in the spreadsheet, the basic web
+// colors plus the greys used for drafting.
+var colorPalette = map[string]Color{
+	"black":     {0, 0, 0},
+	"white":     {255, 255, 255},
+	"red":       {255, 0, 0},
+	"green":     {0, 128, 0},
+	"lime":      {0, 255, 0},
+	"blue":      {0, 0, 255},
+	"yellow":    {255, 255, 0},
+	"cyan":      {0, 255, 255},
+	"magenta":   {255, 0, 255},
+	"orange":    {255, 165, 0},
+	"brown":     {165, 42, 42},
+	"maroon":    {128, 0, 0},
+	"olive":     {128, 128, 0},
+	"navy":      {0, 0, 128},
+	"purple":    {128, 0, 128},
+	"teal":      {0, 128, 128},
+	"silver":    {192, 192, 192},
+	"gray":      {128, 128, 128},
+	"grey":      {128, 128, 128},
+	"lightgray": {211, 211, 211},
+	"lightgrey": {211, 211, 211},
+	"darkgray":  {169, 169, 169},
+	"darkgrey":  {169, 169, 169},
+}
+
+// InvalidColor is a pattern color cell of MATERIALS that couldn't be parsed,
+// the material reads without the color until the cell is fixed.
+type InvalidColor struct {
+	Material string
+	Field    string
+	Value    string
+	Row      int
+	Error    string
+}
+
+type InvalidColors []*InvalidColor
+
+// ParseColor accepts hex colors ("#F00", "#FF0000", "FF0000"), RGB triplets
+// ("255,0,0", "255;0;0", "rgb(255, 0, 0)", Revit's "RGB 255-000-000") and
+// the names of the palette.
+func ParseColor(value string) (*Color, error) {
+	trimmed := strings.TrimSpace(value)
+
+	if color, ok := colorPalette[strings.ToLower(strings.Join(strings.Fields(trimmed), ""))]; ok {
+		return &color, nil
+	}
+
+	if match := colorHex.FindStringSubmatch(trimmed); match != nil {
+		digits := match[1]
+		if len(digits) == 3 {
+			digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+		}
+
+		number, _ := strconv.ParseUint(digits, 16, 32)
+
+		return &Color{R: uint8(number >> 16), G: uint8(number >> 8), B: uint8(number)}, nil
+	}
+
+	if match := colorTriplet.FindStringSubmatch(trimmed); match != nil {
+		components := [3]uint8{}
+
+		for index, component := range match[1:] {
+			number, err := strconv.Atoi(component)
+			if err != nil || number > 255 {
+				return nil, errors.Wrapf(ErrInvalid, "color component %s of %q out of range", component, value)
+			}
+
+			components[index] = uint8(number)
+		}
+
+		return &Color{R: components[0], G: components[1], B: components[2]}, nil
+	}
+
+	return nil, errors.Wrapf(ErrInvalid, "unknown color %q", value)
+}
+
+// Hex formats the color as #RRGGBB.
+func (c Color) Hex() string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+func (c Color) String() string {
+	return c.Hex()
+}
+
+type colorJSON struct {
+	Hex string
+	R   uint8
+	G   uint8
+	B   uint8
+}
+
+// MarshalJSON writes both forms so the add-in can build a Revit color from
+// the components and people can read the hex value.
+func (c Color) MarshalJSON() ([]byte, error) {
+	return json.Marshal(colorJSON{Hex: c.Hex(), R: c.R, G: c.G, B: c.B})
+}
+
+// UnmarshalJSON accepts anything ParseColor does, or the object written by
+// MarshalJSON where Hex wins over the components.
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		color, err := ParseColor(value)
+		if err != nil {
+			return err
+		}
+
+		*c = *color
+
+		return nil
+	}
+
+	var object struct {
+		Hex     string
+		R, G, B *int
+	}
+
+	if err := json.Unmarshal(data, &object); err != nil {
+		return errors.Wrap(ErrInvalid, "color must be a string or an object")
+	}
+
+	if object.Hex != "" {
+		color, err := ParseColor(object.Hex)
+		if err != nil {
+			return err
+		}
+
+		*c = *color
+
+		return nil
+	}
+
+	if object.R == nil || object.G == nil || object.B == nil {
+		return errors.Wrap(ErrInvalid, "color needs either Hex or R, G and B")
+	}
+
+	color, err := ParseColor(fmt.Sprintf("%d,%d,%d", *object.R, *object.G, *object.B))
+	if err != nil {
+		return err
+	}
+
+	*c = *color
+
+	return nil
+}
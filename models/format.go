@@ -0,0 +1,143 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatYAML Format = "yaml"
+)
+
+var formatContentTypes = map[Format]string{
+	FormatJSON: "application/json",
+	FormatCSV:  "text/csv; charset=utf-8",
+	FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	FormatYAML: "application/yaml; charset=utf-8",
+}
+
+var formatMediaTypeAliases = map[string]Format{
+	"text/yaml":          FormatYAML,
+	"text/x-yaml":        FormatYAML,
+	"application/x-yaml": FormatYAML,
+}
+
+func ParseFormat(value string) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimSpace(value)))
+	if format == "" {
+		return FormatJSON, nil
+	}
+
+	if _, ok := formatContentTypes[format]; !ok {
+		return "", errors.Wrapf(ErrInvalid, "unknown format %q", value)
+	}
+
+	return format, nil
+}
+
+func (f Format) ContentType() string {
+	return formatContentTypes[f]
+}
+
+// FormatFromContentType maps the media type of an uploaded body to a format,
+// anything unknown is taken as JSON.
+func FormatFromContentType(contentType string) Format {
+	if format, ok := formatFromMediaType(contentType); ok {
+		return format
+	}
+
+	return FormatJSON
+}
+
+func formatFromMediaType(contentType string) (Format, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	for format, formatContentType := range formatContentTypes {
+		formatMediaType, _, _ := strings.Cut(formatContentType, ";")
+		if mediaType == formatMediaType {
+			return format, true
+		}
+	}
+
+	format, ok := formatMediaTypeAliases[mediaType]
+
+	return format, ok
+}
+
+// FormatFromAccept picks the known format with the highest quality in an
+// Accept header, wildcards and unknown media types give JSON.
+func FormatFromAccept(accept string) Format {
+	best, bestQuality := FormatJSON, 0.0
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+		format, ok := formatFromMediaType(mediaRange)
+		if !ok {
+			continue
+		}
+
+		quality := 1.0
+
+		_, parameters, _ := strings.Cut(mediaRange, ";")
+		for _, parameter := range strings.Split(parameters, ";") {
+			name, value, _ := strings.Cut(parameter, "=")
+			if strings.TrimSpace(name) != "q" {
+				continue
+			}
+
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = parsed
+			}
+		}
+
+		if quality > bestQuality {
+			best, bestQuality = format, quality
+		}
+	}
+
+	return best
+}
+
+// ReadOptions tweaks how the read endpoints write their data.
+type ReadOptions struct {
+	Units  LengthUnit
+	Format Format
+}
+
+type GraphFormat string
+
+const (
+	GraphFormatDOT     GraphFormat = "dot"
+	GraphFormatGraphML GraphFormat = "graphml"
+	GraphFormatJSON    GraphFormat = "json"
+)
+
+var graphFormatContentTypes = map[GraphFormat]string{
+	GraphFormatDOT:     "text/vnd.graphviz; charset=utf-8",
+	GraphFormatGraphML: "application/graphml+xml",
+	GraphFormatJSON:    "application/json",
+}
+
+func ParseGraphFormat(value string) (GraphFormat, error) {
+	format := GraphFormat(strings.ToLower(strings.TrimSpace(value)))
+	if format == "" {
+		return GraphFormatJSON, nil
+	}
+
+	if _, ok := graphFormatContentTypes[format]; !ok {
+		return "", errors.Wrapf(ErrInvalid, "unknown graph format %q", value)
+	}
+
+	return format, nil
+}
+
+func (f GraphFormat) ContentType() string {
+	return graphFormatContentTypes[f]
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// LengthUnit is the unit thicknesses are written in, internally they are
+// always millimeters.
+type LengthUnit string
+
+const (
+	UnitMillimeter LengthUnit = "mm"
+	UnitCentimeter LengthUnit = "cm"
+	UnitMeter      LengthUnit = "m"
+	UnitInch       LengthUnit = "in"
+	UnitFoot       LengthUnit = "ft"
+)
+
+var lengthUnitMillimeters = map[LengthUnit]float64{
+	UnitMillimeter: 1,
+	UnitCentimeter: 10,
+	UnitMeter:      1000,
+	UnitInch:       25.4,
+	UnitFoot:       304.8,
+}
+
+var lengthUnitAliases = map[string]LengthUnit{
+	"millimeter":  UnitMillimeter,
+	"millimeters": UnitMillimeter,
+	"millimetre":  UnitMillimeter,
+	"millimetres": UnitMillimeter,
+	"centimeter":  UnitCentimeter,
+	"centimeters": UnitCentimeter,
+	"centimetre":  UnitCentimeter,
+	"centimetres": UnitCentimeter,
+	"meter":       UnitMeter,
+	"meters":      UnitMeter,
+	"metre":       UnitMeter,
+	"metres":      UnitMeter,
+	"inch":        UnitInch,
+	"inches":      UnitInch,
+	`"`:           UnitInch,
+	"foot":        UnitFoot,
+	"feet":        UnitFoot,
+	"'":           UnitFoot,
+	// Revit stores every length in decimal feet.
+	"revit": UnitFoot,
+}
+
+// ParseLengthUnit defaults to millimeters, the unit of the spreadsheets
+// written before units could be declared.
+func ParseLengthUnit(value string) (LengthUnit, error) {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	if normalized == "" {
+		return UnitMillimeter, nil
+	}
+
+	if _, ok := lengthUnitMillimeters[LengthUnit(normalized)]; ok {
+		return LengthUnit(normalized), nil
+	}
+
+	if unit, ok := lengthUnitAliases[normalized]; ok {
+		return unit, nil
+	}
+
+	return "", errors.Wrapf(ErrInvalid, "unknown length unit %q", value)
+}
+
+// millimeters is the size of the unit, the zero value standing for
+// millimeters like ParseLengthUnit("") does.
+func (u LengthUnit) millimeters() float64 {
+	if factor, ok := lengthUnitMillimeters[u]; ok {
+		return factor
+	}
+
+	return 1
+}
+
+func (u LengthUnit) ToMillimeters(value float64) float64 {
+	return value * u.millimeters()
+}
+
+func (u LengthUnit) FromMillimeters(value float64) float64 {
+	return value / u.millimeters()
+}
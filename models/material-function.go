@@ -0,0 +1,126 @@
+package models
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// MaterialFunction mirrors Revit's MaterialFunctionAssignment, the values are
+// the ones used by the Revit API so the add-in can cast them directly.
+type MaterialFunction int
+
+const (
+	FunctionNone       MaterialFunction = 0
+	FunctionStructure  MaterialFunction = 1
+	FunctionSubstrate  MaterialFunction = 2
+	FunctionInsulation MaterialFunction = 3
+	FunctionFinish1    MaterialFunction = 4
+	FunctionFinish2    MaterialFunction = 5
+	FunctionMembrane   MaterialFunction = 100
+)
+
+var materialFunctionNames = map[MaterialFunction]string{
+	FunctionStructure:  "Structure",
+	FunctionSubstrate:  "Substrate",
+	FunctionInsulation: "Insulation",
+	FunctionFinish1:    "Finish1",
+	FunctionFinish2:    "Finish2",
+	FunctionMembrane:   "Membrane",
+}
+
+var materialFunctionAliases = map[string]MaterialFunction{
+	"structure":       FunctionStructure,
+	"structural":      FunctionStructure,
+	"core":            FunctionStructure,
+	"substrate":       FunctionSubstrate,
+	"insulation":      FunctionInsulation,
+	"thermal":         FunctionInsulation,
+	"thermalair":      FunctionInsulation,
+	"thermalairlayer": FunctionInsulation,
+	"finish":          FunctionFinish1,
+	"finish1":         FunctionFinish1,
+	"finish2":         FunctionFinish2,
+	"membrane":        FunctionMembrane,
+	"membranelayer":   FunctionMembrane,
+}
+
+// ParseMaterialFunction accepts the Revit names, the labels shown in the
+// Revit UI ("Thermal/Air Layer", "Finish 1 [4]", ...) and the numeric values.
+func ParseMaterialFunction(value string) (MaterialFunction, error) {
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '/', '-', '_', '[', ']', '(', ')':
+			return -1
+		}
+
+		return r
+	}, strings.ToLower(value))
+
+	if number, err := strconv.Atoi(normalized); err == nil {
+		if _, ok := materialFunctionNames[MaterialFunction(number)]; ok {
+			return MaterialFunction(number), nil
+		}
+	}
+
+	if function, ok := materialFunctionAliases[normalized]; ok {
+		return function, nil
+	}
+
+	// Revit labels carry the numeric value, e.g. "Finish 1 [4]".
+	for alias, function := range materialFunctionAliases {
+		if normalized == alias+strconv.Itoa(int(function)) {
+			return function, nil
+		}
+	}
+
+	return FunctionNone, errors.Wrapf(ErrInvalid, "unknown material function %q", value)
+}
+
+func (f MaterialFunction) String() string {
+	if name, ok := materialFunctionNames[f]; ok {
+		return name
+	}
+
+	return ""
+}
+
+func (f MaterialFunction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
+func (f *MaterialFunction) UnmarshalJSON(data []byte) error {
+	var number int
+	if err := json.Unmarshal(data, &number); err == nil {
+		function, err := ParseMaterialFunction(strconv.Itoa(number))
+		if err != nil {
+			return err
+		}
+
+		*f = function
+
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.Wrap(ErrInvalid, "material function must be a string or a number")
+	}
+
+	if value == "" {
+		*f = FunctionNone
+
+		return nil
+	}
+
+	function, err := ParseMaterialFunction(value)
+	if err != nil {
+		return err
+	}
+
+	*f = function
+
+	return nil
+}
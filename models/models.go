@@ -16,23 +16,32 @@ const (
 type Material struct {
 	Name                          *string
 	MaterialCategory              *string
-	CutBackgroundPatternColor     *string
+	CutBackgroundPatternColor     *Color
 	CutBackgroundPatternId        *string
-	CutForegroundPatternColor     *string
+	CutForegroundPatternColor     *Color
 	CutForegroundPatternId        *string
-	SurfaceForegroundPatternColor *string
+	SurfaceForegroundPatternColor *Color
 	SurfaceForegroundPatternId    *string
 	Mark                          *string
 	Keynote                       *string
 	Description                   *string
 	Manufacturer                  *string
+	Conductivity                  *float64
+	Density                       *float64
+	SpecificHeat                  *float64
+	CarbonFactor                  *float64
+	UnitPrice                     *float64
+	Unit                          *string
+	Currency                      *string
+	FireRating                    *float64
+	AcousticRating                *float64
 }
 
 type Materials []*Material
 
 type WallMaterial struct {
 	Thickness    float64
-	Function     string
+	Function     MaterialFunction
 	IsStructural bool
 	Material     *Material
 }
@@ -40,7 +49,12 @@ type WallMaterial struct {
 type WallMaterials []*WallMaterial
 
 type Area struct {
-	Name string
+	Name       string
+	Type       AreaType
+	Level      *string
+	Building   *string
+	UseClass   *string
+	Properties map[string]string
 }
 
 type Areas []*Area
@@ -53,11 +67,251 @@ type AreaMaterials struct {
 type AreasMaterials []*AreaMaterials
 
 type AreaRelation struct {
-	AreaInternal *Area
-	AreaExternal *Area
-	Central      *WallMaterial
-	WallKeynote  *string
-	SameArea     bool
+	AreaInternal   *Area
+	AreaExternal   *Area
+	Central        *WallMaterial
+	WallKeynote    *string
+	SameArea       bool
+	WallArea       *float64
+	FireRating     *float64
+	AcousticRating *float64
 }
 
 type AreasRelations []*AreaRelation
+
+type WallLayer struct {
+	Thickness    float64
+	Function     MaterialFunction
+	IsStructural bool
+	IsCore       bool
+	Area         *Area
+	Material     *Material
+}
+
+type WallLayers []*WallLayer
+
+type WallAssembly struct {
+	Relation       *AreaRelation
+	WallType       string
+	Exterior       bool
+	Layers         WallLayers
+	TotalThickness float64
+}
+
+type WallAssemblies []*WallAssembly
+
+type WallType struct {
+	Name           string
+	Fingerprint    string
+	TotalThickness float64
+	Layers         WallLayers
+	Relations      AreasRelations
+}
+
+type WallTypes []*WallType
+
+type WallRuleViolation struct {
+	WallType string
+	Relation *AreaRelation
+	Rule     string
+	Layer    int
+	Message  string
+}
+
+type WallRuleViolations []*WallRuleViolation
+
+type WallLayerThermal struct {
+	Material     *string
+	Thickness    float64
+	Conductivity *float64
+	RValue       *float64
+}
+
+type WallThermal struct {
+	WallType  string
+	Relation  *AreaRelation
+	Layers    []*WallLayerThermal
+	Rsi       float64
+	Rse       float64
+	RValue    float64
+	UValue    *float64
+	TargetU   float64
+	Complete  bool
+	Compliant bool
+}
+
+type WallsThermal []*WallThermal
+
+type WallLayerCarbon struct {
+	Material     *string
+	Thickness    float64
+	Density      *float64
+	CarbonFactor *float64
+	Carbon       *float64
+}
+
+type WallTypeCarbon struct {
+	WallType    string
+	Layers      []*WallLayerCarbon
+	Carbon      float64
+	Complete    bool
+	WallArea    float64
+	TotalCarbon float64
+}
+
+type ProjectCarbon struct {
+	WallTypes     []*WallTypeCarbon
+	WallArea      float64
+	TotalCarbon   float64
+	AverageCarbon *float64
+	Complete      bool
+}
+
+type WallInstance struct {
+	AreaInternal string
+	AreaExternal string
+	Length       float64
+	Height       float64
+	OpeningsArea float64
+}
+
+type WallInstances []*WallInstance
+
+type MaterialQuantity struct {
+	Material string
+	Area     float64
+	Volume   float64
+}
+
+type MaterialQuantities []*MaterialQuantity
+
+type MaterialCost struct {
+	Material  string
+	Quantity  float64
+	Unit      string
+	UnitPrice *float64
+	Currency  *string
+	Cost      *float64
+}
+
+type CostReport struct {
+	Currency  string
+	Materials []*MaterialCost
+	Total     float64
+	Complete  bool
+}
+
+type AreaRequirement struct {
+	AreaA          string
+	AreaB          string
+	FireRating     *float64
+	AcousticRating *float64
+}
+
+type AreasRequirements []*AreaRequirement
+
+type WallRatingCheck struct {
+	WallType               string
+	Relation               *AreaRelation
+	RequiredFireRating     *float64
+	FireRating             *float64
+	RequiredAcousticRating *float64
+	AcousticRating         *float64
+	Violations             []string
+}
+
+type WallRatingChecks []*WallRatingCheck
+
+type AreaAdjacency struct {
+	AreaA string
+	AreaB string
+}
+
+type AreasAdjacencies []*AreaAdjacency
+
+type RelationConflict struct {
+	Relations   AreasRelations
+	Differences []string
+}
+
+type RelationsAnalysis struct {
+	MissingRelations             AreasAdjacencies
+	ConflictingRelations         []*RelationConflict
+	SelfRelationsWithoutSameArea AreasRelations
+	UnusedAreas                  Areas
+	UnknownAreas                 []string
+}
+
+// AreasGraph follows the node-link layout used by d3 and networkx, hence the
+// lower case JSON names. Relations without external area point to the
+// exterior node, the only one without area.
+type AreasGraph struct {
+	Directed bool             `json:"directed"`
+	Nodes    []*AreaGraphNode `json:"nodes"`
+	Links    []*AreaGraphLink `json:"links"`
+}
+
+type AreaGraphNode struct {
+	ID   string `json:"id"`
+	Area *Area  `json:"area"`
+}
+
+type AreaGraphLink struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Central  string `json:"central,omitempty"`
+	Keynote  string `json:"keynote,omitempty"`
+	SameArea bool   `json:"sameArea"`
+	WallType string `json:"wallType,omitempty"`
+}
+
+type RelationsMatrixCell struct {
+	Central  string
+	Keynote  string
+	WallType string
+}
+
+// RelationsMatrix has one row per internal area and one column per external
+// area, cells without relation are nil.
+type RelationsMatrix struct {
+	Rows    []string
+	Columns []string
+	Cells   [][]*RelationsMatrixCell
+}
+
+type KeynoteEntry struct {
+	Key    string
+	Text   string
+	Parent string
+}
+
+type KeynoteEntries []*KeynoteEntry
+
+type MissingKeynote struct {
+	Keynote string
+	Source  string
+}
+
+type KeynotesValidation struct {
+	Entries         int
+	MissingKeynotes []*MissingKeynote
+	UnknownParents  KeynoteEntries
+}
+
+type FillPattern struct {
+	Name        string
+	Description string
+	Type        string
+	Units       string
+	Lines       []string
+}
+
+type FillPatterns []*FillPattern
+
+type MissingPattern struct {
+	Material string
+	Field    string
+	Pattern  string
+}
+
+type MissingPatterns []*MissingPattern
@@ -2,7 +2,6 @@ package spreadsheet
 
 import (
 	"context"
-	"encoding/json"
 	"io"
 	"log"
 
@@ -92,15 +91,15 @@ func (s *Spreadsheet) getAreasMaterials(ctx context.Context) error {
 	return nil
 }
 
-func (s *Spreadsheet) ReadAreasMaterialsTo(ctx context.Context, dst io.Writer) error {
+func (s *Spreadsheet) ReadAreasMaterialsTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error {
 	if s.areasMaterials == nil {
 		if err := s.getAreasMaterials(ctx); err != nil {
 			return errors.Wrap(err, "Unable to read areas materials from spreadsheet")
 		}
 	}
 
-	if err := json.NewEncoder(dst).Encode(s.areasMaterials); err != nil {
-		return errors.Wrap(err, "Unable to encode areas materials to JSON")
+	if err := writeData(dst, options.Format, "Areas materials", convertAreasMaterials(s.areasMaterials, options.Units)); err != nil {
+		return errors.Wrap(err, "Unable to encode areas materials")
 	}
 
 	return nil
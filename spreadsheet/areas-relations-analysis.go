@@ -0,0 +1,224 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// relationPair identifies the areas of a relation regardless of direction,
+// relations without external area use an empty name.
+type relationPair struct {
+	a, b string
+}
+
+func newRelationPair(a, b string) relationPair {
+	if a > b {
+		a, b = b, a
+	}
+
+	return relationPair{a: a, b: b}
+}
+
+func pairOfRelation(relation *models.AreaRelation) relationPair {
+	var (
+		internal, external string
+	)
+
+	if relation.AreaInternal != nil {
+		internal = relation.AreaInternal.Name
+	}
+
+	switch {
+	case relation.SameArea:
+		external = internal
+	case relation.AreaExternal != nil:
+		external = relation.AreaExternal.Name
+	}
+
+	return newRelationPair(internal, external)
+}
+
+func analyzeAreasRelations(areas models.Areas, relations models.AreasRelations, adjacencies models.AreasAdjacencies) *models.RelationsAnalysis {
+	analysis := &models.RelationsAnalysis{
+		MissingRelations:             models.AreasAdjacencies{},
+		ConflictingRelations:         []*models.RelationConflict{},
+		SelfRelationsWithoutSameArea: models.AreasRelations{},
+		UnusedAreas:                  models.Areas{},
+		UnknownAreas:                 []string{},
+	}
+
+	pairs := []relationPair{}
+	relationsByPair := map[relationPair]models.AreasRelations{}
+	usedAreas := map[string]bool{}
+
+	for _, relation := range relations {
+		pair := pairOfRelation(relation)
+		if _, ok := relationsByPair[pair]; !ok {
+			pairs = append(pairs, pair)
+		}
+
+		relationsByPair[pair] = append(relationsByPair[pair], relation)
+
+		if relation.AreaInternal != nil {
+			usedAreas[relation.AreaInternal.Name] = true
+		}
+
+		if relation.AreaExternal != nil {
+			usedAreas[relation.AreaExternal.Name] = true
+
+			if relation.AreaInternal != nil &&
+				relation.AreaInternal.Name == relation.AreaExternal.Name &&
+				!relation.SameArea {
+				analysis.SelfRelationsWithoutSameArea = append(analysis.SelfRelationsWithoutSameArea, relation)
+			}
+		}
+	}
+
+	for _, pair := range pairs {
+		pairRelations := relationsByPair[pair]
+		if len(pairRelations) < 2 {
+			continue
+		}
+
+		differences := relationsDifferences(pairRelations)
+		if len(differences) > 0 {
+			analysis.ConflictingRelations = append(analysis.ConflictingRelations, &models.RelationConflict{
+				Relations:   pairRelations,
+				Differences: differences,
+			})
+		}
+	}
+
+	knownAreas := map[string]bool{}
+	for _, area := range areas {
+		knownAreas[area.Name] = true
+
+		if !usedAreas[area.Name] {
+			analysis.UnusedAreas = append(analysis.UnusedAreas, area)
+		}
+	}
+
+	unknownAreas := map[string]bool{}
+	for _, adjacency := range adjacencies {
+		for _, name := range []string{adjacency.AreaA, adjacency.AreaB} {
+			if name != "" && !knownAreas[name] && !unknownAreas[name] {
+				unknownAreas[name] = true
+				analysis.UnknownAreas = append(analysis.UnknownAreas, name)
+			}
+		}
+
+		if _, ok := relationsByPair[newRelationPair(adjacency.AreaA, adjacency.AreaB)]; !ok {
+			analysis.MissingRelations = append(analysis.MissingRelations, adjacency)
+		}
+	}
+
+	return analysis
+}
+
+// relationsDifferences lists the fields on which relations between the same
+// pair of areas disagree.
+func relationsDifferences(relations models.AreasRelations) []string {
+	differences := []string{}
+	first := relations[0]
+
+	differs := func(equal func(relation *models.AreaRelation) bool) bool {
+		for _, relation := range relations[1:] {
+			if !equal(relation) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if differs(func(relation *models.AreaRelation) bool {
+		return centralMaterialName(relation) == centralMaterialName(first)
+	}) {
+		differences = append(differences, "Central")
+	}
+
+	if differs(func(relation *models.AreaRelation) bool {
+		return equalStrings(relation.WallKeynote, first.WallKeynote)
+	}) {
+		differences = append(differences, "WallKeynote")
+	}
+
+	if differs(func(relation *models.AreaRelation) bool {
+		return relation.SameArea == first.SameArea
+	}) {
+		differences = append(differences, "SameArea")
+	}
+
+	if differs(func(relation *models.AreaRelation) bool {
+		return equalNumbers(relation.FireRating, first.FireRating)
+	}) {
+		differences = append(differences, "FireRating")
+	}
+
+	if differs(func(relation *models.AreaRelation) bool {
+		return equalNumbers(relation.AcousticRating, first.AcousticRating)
+	}) {
+		differences = append(differences, "AcousticRating")
+	}
+
+	return differences
+}
+
+func centralMaterialName(relation *models.AreaRelation) string {
+	if relation.Central == nil || relation.Central.Material == nil || relation.Central.Material.Name == nil {
+		return ""
+	}
+
+	return *relation.Central.Material.Name
+}
+
+func equalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalNumbers(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+// ReadAreasRelationsAnalysisTo reads an optional adjacency list from src, an
+// empty body only skips the missing relations check.
+func (s *Spreadsheet) ReadAreasRelationsAnalysisTo(ctx context.Context, src io.Reader, dst io.Writer) error {
+	var adjacencies models.AreasAdjacencies
+
+	if err := json.NewDecoder(src).Decode(&adjacencies); err != nil && !errors.Is(err, io.EOF) {
+		return errors.Wrap(models.ErrInvalid, "Unable to decode adjacencies from JSON: "+err.Error())
+	}
+
+	for index, adjacency := range adjacencies {
+		if adjacency == nil {
+			return errors.Wrapf(models.ErrInvalid, "null adjacency %v", index)
+		}
+	}
+
+	if s.relations == nil {
+		if err := s.getAreasRelations(ctx); err != nil {
+			return errors.Wrap(err, "Unable to read areas relations from spreadsheet")
+		}
+	}
+
+	analysis := analyzeAreasRelations(s.areas, s.relations, adjacencies)
+
+	if err := json.NewEncoder(dst).Encode(analysis); err != nil {
+		return errors.Wrap(err, "Unable to encode areas relations analysis to JSON")
+	}
+
+	return nil
+}
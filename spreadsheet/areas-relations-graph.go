@@ -0,0 +1,259 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// exteriorNodeID names the node standing for the outside of the building,
+// the target of the relations without external area. Areas can be called
+// anything, the ID is wrapped in parentheses until no area has it.
+func exteriorNodeID(areas models.Areas) string {
+	names := map[string]bool{}
+	for _, area := range areas {
+		names[area.Name] = true
+	}
+
+	id := "(exterior)"
+	for names[id] {
+		id = "(" + id + ")"
+	}
+
+	return id
+}
+
+// buildAreasGraph keeps the areas matching filter as nodes and the relations
+// between two kept areas as links, relations without external area link to
+// the exterior node, added on first use.
+func buildAreasGraph(areas models.Areas, assemblies models.WallAssemblies, filter models.AreaFilter) *models.AreasGraph {
+	exterior := exteriorNodeID(areas)
+
+	graph := &models.AreasGraph{
+		Directed: true,
+		Nodes:    []*models.AreaGraphNode{},
+		Links:    []*models.AreaGraphLink{},
+	}
+
+	nodes := map[string]bool{}
+	for _, area := range areas {
+		if !filter.Matches(area) {
+			continue
+		}
+
+		nodes[area.Name] = true
+		graph.Nodes = append(graph.Nodes, &models.AreaGraphNode{
+			ID:   area.Name,
+			Area: area,
+		})
+	}
+
+	for _, assembly := range assemblies {
+		relation := assembly.Relation
+		if relation.AreaInternal == nil {
+			continue
+		}
+
+		target := exterior
+		switch {
+		case relation.SameArea:
+			target = relation.AreaInternal.Name
+		case relation.AreaExternal != nil:
+			target = relation.AreaExternal.Name
+		}
+
+		if !nodes[relation.AreaInternal.Name] {
+			continue
+		}
+
+		if target == exterior && !nodes[exterior] {
+			nodes[exterior] = true
+			graph.Nodes = append(graph.Nodes, &models.AreaGraphNode{ID: exterior})
+		}
+
+		if !nodes[target] {
+			continue
+		}
+
+		link := &models.AreaGraphLink{
+			Source:   relation.AreaInternal.Name,
+			Target:   target,
+			Central:  centralMaterialName(relation),
+			SameArea: relation.SameArea,
+			WallType: assembly.WallType,
+		}
+
+		if relation.WallKeynote != nil {
+			link.Keynote = *relation.WallKeynote
+		}
+
+		graph.Links = append(graph.Links, link)
+	}
+
+	return graph
+}
+
+func writeAreasGraphDOT(dst io.Writer, graph *models.AreasGraph) error {
+	var builder strings.Builder
+
+	quote := func(value string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`
+	}
+
+	builder.WriteString("digraph areas {\n")
+
+	for _, node := range graph.Nodes {
+		if node.Area == nil {
+			fmt.Fprintf(&builder, "  %s [label=%s, shape=box];\n", quote(node.ID), quote(node.ID))
+			continue
+		}
+
+		fmt.Fprintf(&builder, "  %s [label=%s, type=%s];\n", quote(node.ID), quote(node.ID), quote(string(node.Area.Type)))
+	}
+
+	for _, link := range graph.Links {
+		label := strings.TrimSpace(link.Central + "\n" + link.Keynote)
+
+		fmt.Fprintf(&builder, "  %s -> %s [label=%s, central=%s, keynote=%s];\n",
+			quote(link.Source), quote(link.Target), quote(label), quote(link.Central), quote(link.Keynote))
+	}
+
+	builder.WriteString("}\n")
+
+	_, err := io.WriteString(dst, builder.String())
+
+	return err
+}
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func writeAreasGraphGraphML(dst io.Writer, graph *models.AreasGraph) error {
+	document := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "level", For: "node", AttrName: "level", AttrType: "string"},
+			{ID: "building", For: "node", AttrName: "building", AttrType: "string"},
+			{ID: "useClass", For: "node", AttrName: "useClass", AttrType: "string"},
+			{ID: "central", For: "edge", AttrName: "central", AttrType: "string"},
+			{ID: "keynote", For: "edge", AttrName: "keynote", AttrType: "string"},
+			{ID: "sameArea", For: "edge", AttrName: "sameArea", AttrType: "boolean"},
+			{ID: "wallType", For: "edge", AttrName: "wallType", AttrType: "string"},
+		},
+		Graph: graphMLGraph{
+			ID:          "areas",
+			EdgeDefault: "directed",
+		},
+	}
+
+	optional := func(key string, value *string) []graphMLData {
+		if value == nil {
+			return nil
+		}
+
+		return []graphMLData{{Key: key, Value: *value}}
+	}
+
+	for _, node := range graph.Nodes {
+		if node.Area == nil {
+			document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{ID: node.ID})
+			continue
+		}
+
+		data := []graphMLData{{Key: "type", Value: string(node.Area.Type)}}
+		data = append(data, optional("level", node.Area.Level)...)
+		data = append(data, optional("building", node.Area.Building)...)
+		data = append(data, optional("useClass", node.Area.UseClass)...)
+
+		document.Graph.Nodes = append(document.Graph.Nodes, graphMLNode{ID: node.ID, Data: data})
+	}
+
+	for _, link := range graph.Links {
+		document.Graph.Edges = append(document.Graph.Edges, graphMLEdge{
+			Source: link.Source,
+			Target: link.Target,
+			Data: []graphMLData{
+				{Key: "central", Value: link.Central},
+				{Key: "keynote", Value: link.Keynote},
+				{Key: "sameArea", Value: strconv.FormatBool(link.SameArea)},
+				{Key: "wallType", Value: link.WallType},
+			},
+		})
+	}
+
+	if _, err := io.WriteString(dst, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(dst)
+	encoder.Indent("", "  ")
+
+	return encoder.Encode(document)
+}
+
+func (s *Spreadsheet) ReadAreasRelationsGraphTo(ctx context.Context, dst io.Writer, format models.GraphFormat, filter models.AreaFilter) error {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	graph := buildAreasGraph(s.areas, assemblies, filter)
+
+	switch format {
+	case models.GraphFormatDOT:
+		err = writeAreasGraphDOT(dst, graph)
+	case models.GraphFormatGraphML:
+		err = writeAreasGraphGraphML(dst, graph)
+	default:
+		err = json.NewEncoder(dst).Encode(graph)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "Unable to encode areas graph to %s", format)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package spreadsheet
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"arca3/models"
+)
+
+func TestAreasGraphExteriorNode(t *testing.T) {
+	areas := models.Areas{
+		{Name: "Office", Type: models.AreaInterior},
+		{Name: "(exterior)", Type: models.AreaExterior},
+	}
+
+	assemblies := models.WallAssemblies{
+		{Relation: &models.AreaRelation{AreaInternal: areas[0], AreaExternal: areas[1]}, WallType: "WT-01"},
+		{Relation: &models.AreaRelation{AreaInternal: areas[0]}, WallType: "WT-02"},
+	}
+
+	graph := buildAreasGraph(areas, assemblies, models.AreaFilter{})
+
+	if len(graph.Nodes) != 3 {
+		t.Fatalf("got %v nodes, want the 2 areas and the exterior", len(graph.Nodes))
+	}
+
+	exterior := graph.Nodes[2]
+	if exterior.Area != nil || exterior.ID == "(exterior)" {
+		t.Errorf("exterior node %+v collides with an area", exterior)
+	}
+
+	if len(graph.Links) != 2 || graph.Links[0].Target != "(exterior)" || graph.Links[1].Target != exterior.ID {
+		t.Errorf("unexpected links %+v %+v", graph.Links[0], graph.Links[1])
+	}
+
+	encoded, err := json.Marshal(graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{`"directed"`, `"nodes"`, `"links"`, `"id"`, `"source"`, `"target"`} {
+		if !strings.Contains(string(encoded), name) {
+			t.Errorf("node-link JSON lacks %s: %s", name, encoded)
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+const (
+	matrixCorner         = "Internal \\ External"
+	matrixNoExternalArea = "(none)"
+	matrixCellSeparator  = " / "
+)
+
+// buildRelationsMatrix lays the areas out in AREAS order on both axes, a
+// relation flagged as SameArea lands on the diagonal and relations without
+// external area go to an extra "(none)" column. Two relations landing on the
+// same cell, like an area facing itself with and without SameArea, can't be
+// shown and are reported.
+func buildRelationsMatrix(areas models.Areas, assemblies models.WallAssemblies) (*models.RelationsMatrix, error) {
+	matrix := &models.RelationsMatrix{}
+
+	indexes := map[string]int{}
+	for index, area := range areas {
+		indexes[area.Name] = index
+		matrix.Rows = append(matrix.Rows, area.Name)
+		matrix.Columns = append(matrix.Columns, area.Name)
+	}
+
+	for _, assembly := range assemblies {
+		relation := assembly.Relation
+		if relation.AreaExternal == nil && !relation.SameArea {
+			matrix.Columns = append(matrix.Columns, matrixNoExternalArea)
+
+			break
+		}
+	}
+
+	matrix.Cells = make([][]*models.RelationsMatrixCell, len(matrix.Rows))
+	for index := range matrix.Cells {
+		matrix.Cells[index] = make([]*models.RelationsMatrixCell, len(matrix.Columns))
+	}
+
+	filled := map[[2]int]*models.AreaRelation{}
+
+	for _, assembly := range assemblies {
+		relation := assembly.Relation
+		if relation.AreaInternal == nil {
+			continue
+		}
+
+		row, ok := indexes[relation.AreaInternal.Name]
+		if !ok {
+			continue
+		}
+
+		column := len(matrix.Columns) - 1
+		switch {
+		case relation.SameArea:
+			column = row
+		case relation.AreaExternal != nil:
+			if column, ok = indexes[relation.AreaExternal.Name]; !ok {
+				continue
+			}
+		}
+
+		if previous, ok := filled[[2]int{row, column}]; ok {
+			return nil, errors.Errorf("relations %s and %s share the same matrix cell",
+				relationLabel(previous), relationLabel(relation))
+		}
+
+		filled[[2]int{row, column}] = relation
+
+		cell := &models.RelationsMatrixCell{
+			Central:  centralMaterialName(relation),
+			WallType: assembly.WallType,
+		}
+
+		if relation.WallKeynote != nil {
+			cell.Keynote = *relation.WallKeynote
+		}
+
+		matrix.Cells[row][column] = cell
+	}
+
+	return matrix, nil
+}
+
+// relationsMatrixTable joins the parts of the cells with matrixCellSeparator,
+// a central material or a keynote containing it couldn't be read back. The
+// wall type comes last and may contain it.
+func relationsMatrixTable(matrix *models.RelationsMatrix) ([][]interface{}, error) {
+	header := []interface{}{matrixCorner}
+	for _, column := range matrix.Columns {
+		header = append(header, column)
+	}
+
+	rows := [][]interface{}{header}
+
+	for index, name := range matrix.Rows {
+		row := []interface{}{name}
+
+		for _, cell := range matrix.Cells[index] {
+			if cell == nil {
+				row = append(row, "")
+
+				continue
+			}
+
+			for _, part := range []string{cell.Central, cell.Keynote} {
+				if strings.Contains(part, matrixCellSeparator) {
+					return nil, errors.Errorf("%q in %s can't be written to a matrix cell", matrixCellSeparator, part)
+				}
+			}
+
+			row = append(row, strings.Join([]string{cell.Central, cell.Keynote, cell.WallType}, matrixCellSeparator))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// relationsMatrixFromTable reads back the table written by
+// relationsMatrixTable, the wall type part of the cells is ignored. Rows or
+// columns may repeat an area as long as each pair is filled only once.
+func relationsMatrixFromTable(rows [][]string) (*models.RelationsMatrix, error) {
+	if len(rows) == 0 || len(rows[0]) < 2 {
+		return nil, errors.Wrap(models.ErrInvalid, "matrix needs a header row with at least one external area")
+	}
+
+	matrix := &models.RelationsMatrix{}
+	for _, column := range rows[0][1:] {
+		matrix.Columns = append(matrix.Columns, strings.TrimSpace(column))
+	}
+
+	filled := map[[2]string]bool{}
+
+	for _, row := range rows[1:] {
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+
+		name := strings.TrimSpace(row[0])
+		matrix.Rows = append(matrix.Rows, name)
+
+		cells := make([]*models.RelationsMatrixCell, len(matrix.Columns))
+		for index, value := range row[1:] {
+			if index >= len(cells) || strings.TrimSpace(value) == "" {
+				continue
+			}
+
+			pair := [2]string{name, matrix.Columns[index]}
+			if filled[pair] {
+				return nil, errors.Wrapf(models.ErrInvalid, "row %v, column %v: duplicate relation between %s and %s",
+					len(matrix.Rows), index+1, pair[0], pair[1])
+			}
+
+			filled[pair] = true
+
+			parts := strings.SplitN(value, matrixCellSeparator, 3)
+			cell := &models.RelationsMatrixCell{
+				Central: strings.TrimSpace(parts[0]),
+			}
+
+			if len(parts) > 1 {
+				cell.Keynote = strings.TrimSpace(parts[1])
+			}
+
+			cells[index] = cell
+		}
+
+		matrix.Cells = append(matrix.Cells, cells)
+	}
+
+	return matrix, nil
+}
+
+// matrixColumn is the column of relation in the matrix, the internal area for
+// a relation flagged as SameArea.
+func matrixColumn(relation *models.AreaRelation) string {
+	switch {
+	case relation.SameArea:
+		return relation.AreaInternal.Name
+	case relation.AreaExternal != nil:
+		return relation.AreaExternal.Name
+	default:
+		return matrixNoExternalArea
+	}
+}
+
+// relationsFromMatrix turns the filled cells into relations, checking that
+// every area and central material exists. The cells only hold the central
+// material and the keynote, the other columns of the existing relations are
+// kept and a new relation from an area to itself is flagged as SameArea.
+func (s *Spreadsheet) relationsFromMatrix(matrix *models.RelationsMatrix) (models.AreasRelations, error) {
+	relations := models.AreasRelations{}
+
+	existing := map[[2]string]*models.AreaRelation{}
+	for _, relation := range s.relations {
+		pair := [2]string{relation.AreaInternal.Name, matrixColumn(relation)}
+		if _, ok := existing[pair]; !ok {
+			existing[pair] = relation
+		}
+	}
+
+	for rowIndex, rowName := range matrix.Rows {
+		if rowIndex >= len(matrix.Cells) {
+			break
+		}
+
+		areaInternal, err := s.findArea(rowName)
+		if err != nil {
+			return nil, errors.Wrapf(models.ErrInvalid, "row %v: %v", rowIndex+1, err)
+		}
+
+		for columnIndex, cell := range matrix.Cells[rowIndex] {
+			if cell == nil || columnIndex >= len(matrix.Columns) {
+				continue
+			}
+
+			position := fmt.Sprintf("row %v, column %v", rowIndex+1, columnIndex+1)
+
+			relation := &models.AreaRelation{
+				AreaInternal: areaInternal,
+			}
+
+			column := matrixNoExternalArea
+			if columnName := matrix.Columns[columnIndex]; columnName != matrixNoExternalArea {
+				areaExternal, err := s.findArea(columnName)
+				if err != nil {
+					return nil, errors.Wrapf(models.ErrInvalid, "%s: %v", position, err)
+				}
+
+				column = areaExternal.Name
+
+				if areaExternal == areaInternal {
+					relation.SameArea = true
+				} else {
+					relation.AreaExternal = areaExternal
+				}
+			}
+
+			if previous, ok := existing[[2]string{areaInternal.Name, column}]; ok {
+				copied := *previous
+				relation = &copied
+			}
+
+			relation.Central = nil
+			relation.WallKeynote = nil
+
+			if cell.Central != "" {
+				relation.Central, err = s.findMaterial(cell.Central)
+				if err != nil {
+					return nil, errors.Wrapf(models.ErrInvalid, "%s: %v", position, err)
+				}
+			}
+
+			if cell.Keynote != "" {
+				keynote := cell.Keynote
+				relation.WallKeynote = &keynote
+			}
+
+			relations = append(relations, relation)
+		}
+	}
+
+	return relations, nil
+}
+
+func (s *Spreadsheet) ReadAreasRelationsMatrixTo(ctx context.Context, dst io.Writer, format models.Format) error {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	matrix, err := buildRelationsMatrix(s.areas, assemblies)
+	if err != nil {
+		return errors.Wrap(err, "Unable to build areas relations matrix")
+	}
+
+	if format == models.FormatYAML {
+		return errors.Wrap(writeData(dst, format, "AREAS_RELATIONS", matrix), "Unable to encode areas relations matrix to YAML")
+	}
+
+	if format != models.FormatJSON {
+		rows, err := relationsMatrixTable(matrix)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to encode areas relations matrix to %s", format)
+		}
+
+		return errors.Wrapf(writeTable(dst, format, "AREAS_RELATIONS", rows), "Unable to encode areas relations matrix to %s", format)
+	}
+
+	if err := json.NewEncoder(dst).Encode(matrix); err != nil {
+		return errors.Wrap(err, "Unable to encode areas relations matrix to JSON")
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) UploadAreasRelationsMatrixFrom(ctx context.Context, src io.Reader, format models.Format) error {
+	var matrix *models.RelationsMatrix
+
+	if format == models.FormatJSON {
+		if err := json.NewDecoder(src).Decode(&matrix); err != nil {
+			return errors.Wrap(models.ErrInvalid, "Unable to decode areas relations matrix from JSON: "+err.Error())
+		}
+	} else {
+		rows, err := readTable(src, format)
+		if err != nil {
+			return errors.Wrap(err, "Unable to read areas relations matrix")
+		}
+
+		if matrix, err = relationsMatrixFromTable(rows); err != nil {
+			return errors.Wrap(err, "Unable to read areas relations matrix")
+		}
+	}
+
+	if matrix == nil {
+		return errors.Wrap(models.ErrInvalid, "empty areas relations matrix")
+	}
+
+	// The matrix only holds part of the relations, the rest is read fresh
+	// from the sheet since it's written back as is.
+	s.ResetData()
+
+	if err := s.getAreasRelations(ctx); err != nil {
+		return errors.Wrap(err, "Unable to get areas relations")
+	}
+
+	relations, err := s.relationsFromMatrix(matrix)
+	if err != nil {
+		return errors.Wrap(err, "Unable to convert areas relations matrix")
+	}
+
+	if len(relations) == 0 {
+		return errors.Wrap(models.ErrInvalid, "empty areas relations")
+	}
+
+	if err := s.uploadAreasRelations(ctx, relations); err != nil {
+		return errors.Wrap(err, "Unable to upload areas relations to spreadsheet")
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package spreadsheet
+
+import (
+	"fmt"
+	"testing"
+
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+// matrixTestSpreadsheet has one relation of each kind, the areas and
+// materials being the cached ones as getAreasRelations would give.
+func matrixTestSpreadsheet() *Spreadsheet {
+	areas := testAreas()
+	materials := testMaterials()
+
+	return &Spreadsheet{
+		areas:     areas,
+		materials: materials,
+		relations: models.AreasRelations{
+			{
+				AreaInternal:   areas[0],
+				AreaExternal:   areas[1],
+				Central:        materials[0],
+				WallKeynote:    stringPtr("W-01"),
+				WallArea:       floatPtr(42.5),
+				FireRating:     floatPtr(60),
+				AcousticRating: floatPtr(45),
+			},
+			{
+				AreaInternal: areas[0],
+				SameArea:     true,
+				WallArea:     floatPtr(12),
+			},
+			{
+				AreaInternal: areas[1],
+				Central:      materials[1],
+			},
+			// An area facing itself without the SameArea flag.
+			{
+				AreaInternal: areas[1],
+				AreaExternal: areas[1],
+				Central:      materials[1],
+				FireRating:   floatPtr(30),
+			},
+		},
+	}
+}
+
+func matrixTestAssemblies(relations models.AreasRelations) models.WallAssemblies {
+	assemblies := models.WallAssemblies{}
+	for index, relation := range relations {
+		assemblies = append(assemblies, &models.WallAssembly{
+			Relation: relation,
+			WallType: fmt.Sprintf("WT-%02d", index+1),
+		})
+	}
+
+	return assemblies
+}
+
+func TestRelationsMatrixRoundTrip(t *testing.T) {
+	s := matrixTestSpreadsheet()
+
+	matrix, err := buildRelationsMatrix(s.areas, matrixTestAssemblies(s.relations))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := relationsMatrixTable(matrix)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := [][]string{}
+	for _, row := range table {
+		cells := []string{}
+		for _, cell := range row {
+			cells = append(cells, fmt.Sprint(cell))
+		}
+
+		rows = append(rows, cells)
+	}
+
+	uploaded, err := relationsMatrixFromTable(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	relations, err := s.relationsFromMatrix(uploaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(relations) != len(s.relations) {
+		t.Fatalf("got %v relations, want %v", len(relations), len(s.relations))
+	}
+
+	want := map[string]*sheets.RowData{}
+	for _, relation := range s.relations {
+		want[relationLabel(relation)] = relationRow(relation)
+	}
+
+	for _, relation := range relations {
+		row, ok := want[relationLabel(relation)]
+		if !ok {
+			t.Errorf("unexpected relation %s", relationLabel(relation))
+
+			continue
+		}
+
+		assertSameRows(t, []*sheets.RowData{row}, []*sheets.RowData{relationRow(relation)})
+	}
+}
+
+func TestRelationsMatrixConflict(t *testing.T) {
+	s := matrixTestSpreadsheet()
+
+	// Lands on the diagonal cell of the relation flagged as SameArea.
+	relations := append(s.relations, &models.AreaRelation{
+		AreaInternal: s.areas[0],
+		AreaExternal: s.areas[0],
+	})
+
+	if _, err := buildRelationsMatrix(s.areas, matrixTestAssemblies(relations)); err == nil {
+		t.Error("expected an error for two relations in the same cell")
+	}
+}
+
+func TestRelationsMatrixSeparator(t *testing.T) {
+	s := matrixTestSpreadsheet()
+	*s.materials[0].Material.Name = "Brick / render"
+
+	matrix, err := buildRelationsMatrix(s.areas, matrixTestAssemblies(s.relations))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := relationsMatrixTable(matrix); err == nil {
+		t.Error("expected an error for a material name containing the separator")
+	}
+
+	uploaded, err := relationsMatrixFromTable([][]string{
+		{matrixCorner, "Outside"},
+		{"Office 1/2", "Concrete, cast in place / W-01 / Wall / exterior"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cell := uploaded.Cells[0][0]; cell.Central != "Concrete, cast in place" || cell.Keynote != "W-01" {
+		t.Errorf("unexpected cell %+v", cell)
+	}
+}
@@ -2,7 +2,7 @@ package spreadsheet
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"io"
 	"log"
 
@@ -24,7 +24,7 @@ func (s *Spreadsheet) getAreasRelations(ctx context.Context) error {
 		}
 	}
 
-	ranges := "AREAS_RELATIONS!A2:E"
+	ranges := "AREAS_RELATIONS!A2:H"
 	result, err := s.client.Spreadsheets.
 		Get(s.spreadsheetID).
 		Context(ctx).
@@ -38,6 +38,7 @@ func (s *Spreadsheet) getAreasRelations(ctx context.Context) error {
 
 	areasKeys := make(models.AreasRelations, 0, len(result.Sheets[0].Data[0].RowData))
 	rowsFromSpreadsheet := result.Sheets[0].Data[0].RowData
+	rows := newSheetRows()
 
 	for index, row := range rowsFromSpreadsheet {
 		var (
@@ -83,42 +84,56 @@ func (s *Spreadsheet) getAreasRelations(ctx context.Context) error {
 		}
 
 		areasKeys = append(areasKeys, &models.AreaRelation{
-			AreaInternal: areaInternal,
-			AreaExternal: areaExternal,
-			Central:      material,
-			SameArea:     sameArea,
-			WallKeynote:  readPtrStringByCellIndex(row, 4),
+			AreaInternal:   areaInternal,
+			AreaExternal:   areaExternal,
+			Central:        material,
+			SameArea:       sameArea,
+			WallKeynote:    readPtrStringByCellIndex(row, 4),
+			WallArea:       readPtrNumberByCellIndex(row, 5),
+			FireRating:     readPtrNumberByCellIndex(row, 6),
+			AcousticRating: readPtrNumberByCellIndex(row, 7),
 		})
+		rows.add(relationKey(areaInternal, areaExternal), index)
 	}
 
 	s.relations = areasKeys
+	s.relationsRows = rows
 
 	return nil
 }
 
-func (s *Spreadsheet) ReadAreasRelationsTo(ctx context.Context, dst io.Writer) error {
+func (s *Spreadsheet) ReadAreasRelationsTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error {
 	if s.relations == nil {
 		if err := s.getAreasRelations(ctx); err != nil {
 			return errors.Wrap(err, "Unable to read areas relations from spreadsheet")
 		}
 	}
 
-	if err := json.NewEncoder(dst).Encode(s.relations); err != nil {
-		return errors.Wrap(err, "Unable to encode areas relations to JSON")
+	if err := writeData(dst, options.Format, "Areas relations", convertAreasRelations(s.relations, options.Units)); err != nil {
+		return errors.Wrap(err, "Unable to encode areas relations")
 	}
 
 	return nil
 }
 
-func (s *Spreadsheet) UploadAreasRelationsFrom(ctx context.Context, src io.Reader) error {
-	var areasRelations models.AreasRelations
+func (s *Spreadsheet) UploadAreasRelationsFrom(ctx context.Context, src io.Reader, format models.Format) error {
+	areasRelations, err := decodeAreasRelations(src, format)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode areas relations")
+	}
+
+	s.ResetData()
+
+	if err := s.loadAreas(ctx); err != nil {
+		return err
+	}
 
-	if err := json.NewDecoder(src).Decode(&areasRelations); err != nil {
-		return errors.Wrap(err, "Unable to decode areas relations from JSON")
+	if err := s.loadMaterials(ctx); err != nil {
+		return err
 	}
 
-	if len(areasRelations) == 0 {
-		return errors.Wrap(models.ErrInvalid, "empty areas relations")
+	if err := s.resolveAreasRelations(areasRelations, format); err != nil {
+		return errors.Wrap(err, "Unable to resolve areas relations")
 	}
 
 	if err := s.uploadAreasRelations(ctx, areasRelations); err != nil {
@@ -128,54 +143,71 @@ func (s *Spreadsheet) UploadAreasRelationsFrom(ctx context.Context, src io.Reade
 	return nil
 }
 
-func (s *Spreadsheet) uploadAreasRelations(ctx context.Context, areasRelations models.AreasRelations) error {
-	requests := []*sheets.Request{}
+// decodeAreasRelations reads the layout written by ReadAreasRelationsTo,
+// areas and the central material are only kept by name.
+func decodeAreasRelations(src io.Reader, format models.Format) (models.AreasRelations, error) {
+	var areasRelations models.AreasRelations
+
+	if err := readData(src, format, &areasRelations); err != nil {
+		return nil, err
+	}
+
+	if len(areasRelations) == 0 {
+		return nil, errors.Wrap(models.ErrInvalid, "empty areas relations")
+	}
 
 	for index, relation := range areasRelations {
-		var (
-			areaInternal, areaExternal *string
-		)
+		if relation == nil || relation.AreaInternal == nil || relation.AreaInternal.Name == "" {
+			return nil, errors.Wrapf(models.ErrInvalid, "areas relation %v has no internal area", index)
+		}
+	}
+
+	return areasRelations, nil
+}
 
-		if relation.AreaInternal != nil {
-			areaInternal = &relation.AreaInternal.Name
+// resolveAreasRelations points the uploaded relations to the areas and
+// materials of the sheet, a name missing from the sheet would break every
+// read of the relations once written.
+func (s *Spreadsheet) resolveAreasRelations(areasRelations models.AreasRelations, format models.Format) error {
+	for index, relation := range areasRelations {
+		position := fmt.Sprintf("areas relation %v", index)
+		if format == models.FormatCSV || format == models.FormatXLSX {
+			position = fmt.Sprintf("row %v", index+2)
 		}
 
-		if relation.AreaExternal != nil {
-			areaExternal = &relation.AreaExternal.Name
+		areaInternal, err := s.findArea(relation.AreaInternal.Name)
+		if err != nil {
+			return errors.Wrap(invalidReference(err), position)
 		}
 
-		requests = append(requests, &sheets.Request{
-			UpdateCells: &sheets.UpdateCellsRequest{
-				Fields: "*",
-				Range: &sheets.GridRange{
-					SheetId:          1715124245,
-					StartRowIndex:    int64(index) + 1,
-					EndRowIndex:      int64(index) + 2,
-					StartColumnIndex: 0,
-					EndColumnIndex:   3,
-				},
-				Rows: []*sheets.RowData{
-					{
-						Values: []*sheets.CellData{
-							{UserEnteredValue: &sheets.ExtendedValue{BoolValue: &relation.SameArea}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: areaInternal}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: areaExternal}},
-						},
-					},
-				},
-			},
-		})
-	}
+		relation.AreaInternal = areaInternal
 
-	if _, err := s.client.Spreadsheets.BatchUpdate(
-		s.spreadsheetID,
-		&sheets.BatchUpdateSpreadsheetRequest{
-			Requests: requests,
-		}).
-		Context(ctx).
-		Do(); err != nil {
-		return err
+		if relation.AreaExternal != nil && relation.AreaExternal.Name != "" {
+			if relation.AreaExternal, err = s.findArea(relation.AreaExternal.Name); err != nil {
+				return errors.Wrap(invalidReference(err), position)
+			}
+		} else {
+			relation.AreaExternal = nil
+		}
+
+		if relation.Central != nil && relation.Central.Material != nil &&
+			relation.Central.Material.Name != nil && *relation.Central.Material.Name != "" {
+			if relation.Central, err = s.findMaterial(*relation.Central.Material.Name); err != nil {
+				return errors.Wrap(invalidReference(err), position)
+			}
+		} else {
+			relation.Central = nil
+		}
 	}
 
 	return nil
 }
+
+func (s *Spreadsheet) uploadAreasRelations(ctx context.Context, areasRelations models.AreasRelations) error {
+	rows := make([]*sheets.RowData, 0, len(areasRelations))
+	for _, relation := range areasRelations {
+		rows = append(rows, relationRow(relation))
+	}
+
+	return s.uploadRows(ctx, areasRelationsSheetID, rows)
+}
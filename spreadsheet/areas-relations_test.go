@@ -0,0 +1,46 @@
+package spreadsheet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+func TestResolveAreasRelations(t *testing.T) {
+	s := &Spreadsheet{areas: testAreas(), materials: testMaterials()}
+
+	relations := testAreasRelations()
+	if err := s.resolveAreasRelations(relations, models.FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	if relations[0].AreaInternal != s.areas[0] || relations[0].Central != s.materials[0] {
+		t.Error("relations don't point to the areas and materials of the sheet")
+	}
+
+	tests := []struct {
+		name     string
+		relation *models.AreaRelation
+	}{
+		{"unknown internal area", &models.AreaRelation{AreaInternal: &models.Area{Name: "Lobby"}}},
+		{"unknown external area", &models.AreaRelation{AreaInternal: s.areas[0], AreaExternal: &models.Area{Name: "Lobby"}}},
+		{"unknown central material", &models.AreaRelation{
+			AreaInternal: s.areas[0],
+			Central:      &models.WallMaterial{Material: &models.Material{Name: stringPtr("Timber")}},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			relations := models.AreasRelations{testAreasRelations()[1], test.relation}
+
+			err := s.resolveAreasRelations(relations, models.FormatCSV)
+			if !errors.Is(err, models.ErrInvalid) || !strings.Contains(err.Error(), "row 3") {
+				t.Errorf("got %v, want an ErrInvalid on row 3", err)
+			}
+		})
+	}
+}
@@ -2,9 +2,10 @@ package spreadsheet
 
 import (
 	"context"
-	"encoding/json"
 	"io"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/pkg/errors"
 	"google.golang.org/api/sheets/v4"
@@ -13,7 +14,7 @@ import (
 )
 
 func (s *Spreadsheet) getAreas(ctx context.Context) error {
-	ranges := "AREAS!A2:A"
+	ranges := "AREAS!A2:F"
 	result, err := s.client.Spreadsheets.
 		Get(s.spreadsheetID).
 		Context(ctx).
@@ -27,6 +28,7 @@ func (s *Spreadsheet) getAreas(ctx context.Context) error {
 
 	areas := make(models.Areas, 0, len(result.Sheets[0].Data[0].RowData))
 	rowsFromSpreadsheet := result.Sheets[0].Data[0].RowData
+	rows := newSheetRows()
 
 	for index, row := range rowsFromSpreadsheet {
 		area, err := readStringByCellIndex(row, 0)
@@ -40,82 +42,147 @@ func (s *Spreadsheet) getAreas(ctx context.Context) error {
 			break
 		}
 
+		areaType := ""
+		if value := readPtrStringByCellIndex(row, 1); value != nil {
+			areaType = *value
+		}
+
+		parsedAreaType, err := models.ParseAreaType(areaType)
+		if err != nil {
+			return errors.Wrapf(err, "error reading type of area %s in row %v", area, index)
+		}
+
 		areas = append(areas, &models.Area{
-			Name: area,
+			Name:       area,
+			Type:       parsedAreaType,
+			Level:      readPtrTextByCellIndex(row, 2),
+			Building:   readPtrTextByCellIndex(row, 3),
+			UseClass:   readPtrStringByCellIndex(row, 4),
+			Properties: parseAreaProperties(readPtrStringByCellIndex(row, 5)),
 		})
+		rows.add(area, index)
 	}
 
 	s.areas = areas
+	s.areasRows = rows
 
 	return nil
 }
 
-func (s *Spreadsheet) ReadAreasTo(ctx context.Context, dst io.Writer) error {
+// parseAreaProperties reads custom properties written as "key=value" pairs
+// separated by semicolons or new lines.
+func parseAreaProperties(value *string) map[string]string {
+	if value == nil {
+		return nil
+	}
+
+	properties := map[string]string{}
+
+	for _, pair := range strings.FieldsFunc(*value, func(r rune) bool { return r == ';' || r == '\n' }) {
+		key, propertyValue, _ := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); key != "" {
+			properties[key] = strings.TrimSpace(propertyValue)
+		}
+	}
+
+	if len(properties) == 0 {
+		return nil
+	}
+
+	return properties
+}
+
+func formatAreaProperties(properties map[string]string) string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+properties[key])
+	}
+
+	return strings.Join(pairs, "; ")
+}
+
+func (s *Spreadsheet) ReadAreasTo(ctx context.Context, dst io.Writer, filter models.AreaFilter, options models.ReadOptions) error {
 	if s.areas == nil {
 		if err := s.getAreas(ctx); err != nil {
 			return errors.Wrap(err, "Unable to read areas from spreadsheet")
 		}
 	}
 
-	if err := json.NewEncoder(dst).Encode(s.areas); err != nil {
-		return errors.Wrap(err, "Unable to encode areas to JSON")
+	areas := make(models.Areas, 0, len(s.areas))
+	for _, area := range s.areas {
+		if filter.Matches(area) {
+			areas = append(areas, area)
+		}
+	}
+
+	if err := writeData(dst, options.Format, "Areas", areas); err != nil {
+		return errors.Wrap(err, "Unable to encode areas")
 	}
 
 	return nil
 }
 
-func (s *Spreadsheet) UploadAreasFrom(ctx context.Context, src io.Reader) error {
-	var areas models.Areas
-
-	if err := json.NewDecoder(src).Decode(&areas); err != nil {
-		return errors.Wrap(err, "Unable to decode areas relations from JSON")
-	}
-
-	if len(areas) == 0 {
-		return errors.Wrap(models.ErrInvalid, "empty areas relations")
+func (s *Spreadsheet) UploadAreasFrom(ctx context.Context, src io.Reader, format models.Format) error {
+	areas, err := decodeAreas(src, format)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode areas")
 	}
 
 	if err := s.uploadAreas(ctx, areas); err != nil {
-		return errors.Wrap(err, "Unable to upload areas relations to spreadsheet")
+		return errors.Wrap(err, "Unable to upload areas to spreadsheet")
 	}
 
 	return nil
 }
 
-func (s *Spreadsheet) uploadAreas(ctx context.Context, areas models.Areas) error {
-	requests := []*sheets.Request{}
+// decodeAreas reads the layout written by ReadAreasTo.
+func decodeAreas(src io.Reader, format models.Format) (models.Areas, error) {
+	var areas models.Areas
 
+	if err := readData(src, format, &areas); err != nil {
+		return nil, err
+	}
+
+	if len(areas) == 0 {
+		return nil, errors.Wrap(models.ErrInvalid, "empty areas")
+	}
+
+	names := map[string]bool{}
 	for index, area := range areas {
-		requests = append(requests, &sheets.Request{
-			UpdateCells: &sheets.UpdateCellsRequest{
-				Fields: "*",
-				Range: &sheets.GridRange{
-					SheetId:          2055988922,
-					StartRowIndex:    int64(index) + 1,
-					EndRowIndex:      int64(index) + 2,
-					StartColumnIndex: 0,
-					EndColumnIndex:   3,
-				},
-				Rows: []*sheets.RowData{
-					{
-						Values: []*sheets.CellData{
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: &area.Name}},
-						},
-					},
-				},
-			},
-		})
+		if area == nil || area.Name == "" {
+			return nil, errors.Wrapf(models.ErrInvalid, "area %v has no name", index)
+		}
+
+		// Relations and per-area requests find areas by name.
+		if names[area.Name] {
+			return nil, errors.Wrapf(models.ErrInvalid, "duplicate area %s", area.Name)
+		}
+
+		names[area.Name] = true
+
+		areaType, err := models.ParseAreaType(string(area.Type))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading type of area %s at index %v", area.Name, index)
+		}
+
+		area.Type = areaType
 	}
 
-	if _, err := s.client.Spreadsheets.BatchUpdate(
-		s.spreadsheetID,
-		&sheets.BatchUpdateSpreadsheetRequest{
-			Requests: requests,
-		}).
-		Context(ctx).
-		Do(); err != nil {
-		return err
+	return areas, nil
+}
+
+func (s *Spreadsheet) uploadAreas(ctx context.Context, areas models.Areas) error {
+	rows := make([]*sheets.RowData, 0, len(areas))
+	for _, area := range areas {
+		rows = append(rows, areaRow(area))
 	}
 
-	return nil
+	return s.uploadRows(ctx, areasSheetID, rows)
 }
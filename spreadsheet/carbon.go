@@ -0,0 +1,94 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// computeWallTypeCarbon estimates the embodied carbon of a wall type in
+// kgCO2e/m², each layer weighs thickness (mm) × density (kg/m³) and is
+// multiplied by its carbon factor (kgCO2e/kg). The wall area of the type is
+// the sum of the areas given on its relations.
+func computeWallTypeCarbon(wallType *models.WallType) *models.WallTypeCarbon {
+	carbon := &models.WallTypeCarbon{
+		WallType: wallType.Name,
+		Complete: true,
+	}
+
+	for _, layer := range wallType.Layers {
+		layerCarbon := &models.WallLayerCarbon{
+			Thickness: layer.Thickness,
+		}
+
+		if layer.Material != nil {
+			layerCarbon.Material = layer.Material.Name
+			layerCarbon.Density = layer.Material.Density
+			layerCarbon.CarbonFactor = layer.Material.CarbonFactor
+		}
+
+		carbon.Layers = append(carbon.Layers, layerCarbon)
+
+		if layer.Thickness == 0 {
+			continue
+		}
+
+		if layerCarbon.Density == nil || layerCarbon.CarbonFactor == nil {
+			carbon.Complete = false
+
+			continue
+		}
+
+		value := layer.Thickness / 1000 * *layerCarbon.Density * *layerCarbon.CarbonFactor
+		layerCarbon.Carbon = &value
+		carbon.Carbon += value
+	}
+
+	for _, relation := range wallType.Relations {
+		if relation.WallArea != nil {
+			carbon.WallArea += *relation.WallArea
+		}
+	}
+
+	carbon.TotalCarbon = carbon.Carbon * carbon.WallArea
+
+	return carbon
+}
+
+func (s *Spreadsheet) ReadWallTypesCarbonTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	project := &models.ProjectCarbon{
+		Complete: true,
+	}
+
+	for _, wallType := range wallTypes {
+		carbon := computeWallTypeCarbon(wallType)
+
+		project.WallTypes = append(project.WallTypes, carbon)
+		project.WallArea += carbon.WallArea
+		project.TotalCarbon += carbon.TotalCarbon
+
+		if !carbon.Complete && carbon.WallArea > 0 {
+			project.Complete = false
+		}
+	}
+
+	if project.WallArea > 0 {
+		average := project.TotalCarbon / project.WallArea
+		project.AverageCarbon = &average
+	}
+
+	if err := json.NewEncoder(dst).Encode(project); err != nil {
+		return errors.Wrap(err, "Unable to encode embodied carbon to JSON")
+	}
+
+	return nil
+}
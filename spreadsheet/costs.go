@@ -0,0 +1,139 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+const (
+	unitSquareMeter = "m2"
+	unitCubicMeter  = "m3"
+)
+
+var costUnitAliases = map[string]string{
+	"m2":  unitSquareMeter,
+	"m²":  unitSquareMeter,
+	"sqm": unitSquareMeter,
+	"m3":  unitCubicMeter,
+	"m³":  unitCubicMeter,
+	"cum": unitCubicMeter,
+}
+
+// convertCurrency converts through the reference currency of the configured
+// rate table.
+func (s *Spreadsheet) convertCurrency(amount float64, from, to string) (float64, bool) {
+	rateFrom, ok := s.currencyRates[strings.ToUpper(from)]
+	if !ok {
+		return 0, false
+	}
+
+	rateTo, ok := s.currencyRates[strings.ToUpper(to)]
+	if !ok {
+		return 0, false
+	}
+
+	return amount * rateFrom / rateTo, true
+}
+
+// computeCosts prices the quantities of the wall instances, a material
+// without price, with an unknown unit or an unknown currency has no cost and
+// leaves the report incomplete. Prices without currency are taken as being
+// in the report currency.
+func (s *Spreadsheet) computeCosts(ctx context.Context, instances models.WallInstances, currency string) (*models.CostReport, error) {
+	if _, ok := s.currencyRates[currency]; !ok {
+		return nil, errors.Wrapf(models.ErrInvalid, "unknown currency %s", currency)
+	}
+
+	quantities, err := s.computeQuantities(ctx, instances)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.CostReport{
+		Currency: currency,
+		Complete: true,
+	}
+
+	for _, quantity := range quantities {
+		cost := &models.MaterialCost{
+			Material: quantity.Material,
+		}
+		report.Materials = append(report.Materials, cost)
+
+		material, err := s.findMaterial(quantity.Material)
+		if err != nil || material.Material == nil || material.Material.UnitPrice == nil || material.Material.Unit == nil {
+			cost.Quantity = quantity.Area
+			cost.Unit = unitSquareMeter
+			report.Complete = false
+
+			continue
+		}
+
+		cost.UnitPrice = material.Material.UnitPrice
+		cost.Currency = material.Material.Currency
+
+		switch costUnitAliases[strings.ToLower(strings.TrimSpace(*material.Material.Unit))] {
+		case unitSquareMeter:
+			cost.Quantity = quantity.Area
+			cost.Unit = unitSquareMeter
+		case unitCubicMeter:
+			cost.Quantity = quantity.Volume
+			cost.Unit = unitCubicMeter
+		default:
+			cost.Unit = *material.Material.Unit
+			report.Complete = false
+
+			continue
+		}
+
+		priceCurrency := currency
+		if cost.Currency != nil && *cost.Currency != "" {
+			priceCurrency = *cost.Currency
+		}
+
+		amount := cost.Quantity * *cost.UnitPrice
+
+		value, ok := s.convertCurrency(amount, priceCurrency, currency)
+		if !ok {
+			report.Complete = false
+
+			continue
+		}
+
+		cost.Cost = &value
+		report.Total += value
+	}
+
+	return report, nil
+}
+
+// ReadCostsTo prices the wall instances read from src in currency, or in the
+// configured cost currency when currency is empty.
+func (s *Spreadsheet) ReadCostsTo(ctx context.Context, src io.Reader, dst io.Writer, currency string) error {
+	var instances models.WallInstances
+
+	if err := json.NewDecoder(src).Decode(&instances); err != nil {
+		return errors.Wrap(models.ErrInvalid, "Unable to decode wall instances from JSON: "+err.Error())
+	}
+
+	if currency == "" {
+		currency = s.costCurrency
+	}
+
+	report, err := s.computeCosts(ctx, instances, strings.ToUpper(currency))
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute costs")
+	}
+
+	if err := json.NewEncoder(dst).Encode(report); err != nil {
+		return errors.Wrap(err, "Unable to encode costs to JSON")
+	}
+
+	return nil
+}
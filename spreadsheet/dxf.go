@@ -0,0 +1,325 @@
+package spreadsheet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// Details are drawn in millimeters, the layers go along X from the exterior
+// face at x = 0 and every wall type is drawn dxfDetailHeight tall, stacked
+// downwards with dxfDetailSpacing between them.
+const (
+	dxfDetailHeight  = 300.0
+	dxfDetailSpacing = 500.0
+	dxfTextHeight    = 8.0
+	dxfHatchSpacing  = 0.125
+
+	dxfAnnotationsLayer = "ANNOTATIONS"
+	dxfHatchPrefix      = "HATCH_"
+)
+
+// dxfWriter writes DXF R12 group code / value pairs.
+type dxfWriter struct {
+	builder strings.Builder
+}
+
+func (w *dxfWriter) pair(code int, value string) {
+	fmt.Fprintf(&w.builder, "%3d\n%s\n", code, value)
+}
+
+func (w *dxfWriter) real(code int, value float64) {
+	w.pair(code, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (w *dxfWriter) integer(code int, value int) {
+	w.pair(code, strconv.Itoa(value))
+}
+
+// dxfName makes a symbol table name valid for R12: upper case letters,
+// digits, $, - and _ only, and at most 31 characters.
+func dxfName(value string) string {
+	var builder strings.Builder
+
+	for _, r := range strings.ToUpper(value) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '$', r == '-', r == '_':
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+
+	name := builder.String()
+	if len(name) > 31 {
+		name = name[:31]
+	}
+
+	if name == "" {
+		name = "UNNAMED"
+	}
+
+	return name
+}
+
+func dxfText(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+func dxfLayerName(layer *models.WallLayer) string {
+	return dxfName(layerMaterialName(layer))
+}
+
+// dxfHatchBlock names the block standing for the cut pattern of a layer, the
+// foreground pattern wins over the background one.
+func dxfHatchBlock(layer *models.WallLayer) string {
+	if layer.Material == nil {
+		return ""
+	}
+
+	for _, pattern := range []*string{layer.Material.CutForegroundPatternId, layer.Material.CutBackgroundPatternId} {
+		if pattern != nil && *pattern != "" {
+			return dxfName(dxfHatchPrefix + *pattern)
+		}
+	}
+
+	return ""
+}
+
+func (w *dxfWriter) line(layer string, x1, y1, x2, y2 float64) {
+	w.pair(0, "LINE")
+	w.pair(8, layer)
+	w.real(10, x1)
+	w.real(20, y1)
+	w.real(30, 0)
+	w.real(11, x2)
+	w.real(21, y2)
+	w.real(31, 0)
+}
+
+func (w *dxfWriter) rectangle(layer string, x, y, width, height float64) {
+	w.pair(0, "POLYLINE")
+	w.pair(8, layer)
+	w.integer(66, 1)
+	w.real(10, 0)
+	w.real(20, 0)
+	w.real(30, 0)
+	w.integer(70, 1)
+
+	for _, point := range [][2]float64{{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}} {
+		w.pair(0, "VERTEX")
+		w.pair(8, layer)
+		w.real(10, point[0])
+		w.real(20, point[1])
+		w.real(30, 0)
+	}
+
+	w.pair(0, "SEQEND")
+	w.pair(8, layer)
+}
+
+func (w *dxfWriter) text(layer string, x, y, rotation float64, value string) {
+	w.pair(0, "TEXT")
+	w.pair(8, layer)
+	w.real(10, x)
+	w.real(20, y)
+	w.real(30, 0)
+	w.real(40, dxfTextHeight)
+	w.pair(1, dxfText(value))
+	w.real(50, rotation)
+}
+
+func (w *dxfWriter) insert(layer, block string, x, y, scaleX, scaleY float64) {
+	w.pair(0, "INSERT")
+	w.pair(8, layer)
+	w.pair(2, block)
+	w.real(10, x)
+	w.real(20, y)
+	w.real(30, 0)
+	w.real(41, scaleX)
+	w.real(42, scaleY)
+	w.real(43, 1)
+}
+
+func dxfLayerAnnotation(layer *models.WallLayer) string {
+	parts := []string{}
+
+	if layer.Material != nil && layer.Material.Keynote != nil && *layer.Material.Keynote != "" {
+		parts = append(parts, *layer.Material.Keynote)
+	}
+
+	parts = append(parts, layerMaterialName(layer), strconv.FormatFloat(layer.Thickness, 'f', -1, 64))
+
+	return strings.Join(parts, " ")
+}
+
+// writeWallTypesDXF writes every wall type as a section detail: a closed
+// polyline per layer on a CAD layer named after its material, the cut
+// pattern as an INSERT of a block scaled to the layer, and keynote texts
+// below the detail. R12 has no HATCH entity, the HATCH_ blocks only hold
+// diagonal lines in a unit square and are meant to be redefined in CAD.
+func writeWallTypesDXF(dst io.Writer, wallTypes models.WallTypes) error {
+	layers := map[string]bool{"0": true, dxfAnnotationsLayer: true}
+	blocks := map[string]bool{}
+
+	for _, wallType := range wallTypes {
+		for _, layer := range wallType.Layers {
+			layers[dxfLayerName(layer)] = true
+
+			if block := dxfHatchBlock(layer); block != "" {
+				blocks[block] = true
+			}
+		}
+	}
+
+	layerNames := sortedKeys(layers)
+	blockNames := sortedKeys(blocks)
+
+	writer := &dxfWriter{}
+
+	writer.pair(0, "SECTION")
+	writer.pair(2, "HEADER")
+	writer.pair(9, "$ACADVER")
+	writer.pair(1, "AC1009")
+	writer.pair(0, "ENDSEC")
+
+	writer.pair(0, "SECTION")
+	writer.pair(2, "TABLES")
+	writer.pair(0, "TABLE")
+	writer.pair(2, "LTYPE")
+	writer.integer(70, 1)
+	writer.pair(0, "LTYPE")
+	writer.pair(2, "CONTINUOUS")
+	writer.integer(70, 0)
+	writer.pair(3, "Solid line")
+	writer.integer(72, 65)
+	writer.integer(73, 0)
+	writer.real(40, 0)
+	writer.pair(0, "ENDTAB")
+	writer.pair(0, "TABLE")
+	writer.pair(2, "LAYER")
+	writer.integer(70, len(layerNames))
+
+	for _, name := range layerNames {
+		writer.pair(0, "LAYER")
+		writer.pair(2, name)
+		writer.integer(70, 0)
+		writer.integer(62, 7)
+		writer.pair(6, "CONTINUOUS")
+	}
+
+	writer.pair(0, "ENDTAB")
+	writer.pair(0, "ENDSEC")
+
+	writer.pair(0, "SECTION")
+	writer.pair(2, "BLOCKS")
+
+	for _, name := range blockNames {
+		writer.pair(0, "BLOCK")
+		writer.pair(8, "0")
+		writer.pair(2, name)
+		writer.integer(70, 0)
+		writer.real(10, 0)
+		writer.real(20, 0)
+		writer.real(30, 0)
+		writer.pair(3, name)
+
+		for offset := dxfHatchSpacing; offset < 2; offset += dxfHatchSpacing {
+			x1, y1 := offset, 0.0
+			if x1 > 1 {
+				x1, y1 = 1, offset-1
+			}
+
+			x2, y2 := 0.0, offset
+			if y2 > 1 {
+				x2, y2 = offset-1, 1
+			}
+
+			writer.line("0", x1, y1, x2, y2)
+		}
+
+		writer.pair(0, "ENDBLK")
+		writer.pair(8, "0")
+	}
+
+	writer.pair(0, "ENDSEC")
+
+	writer.pair(0, "SECTION")
+	writer.pair(2, "ENTITIES")
+
+	for index, wallType := range wallTypes {
+		y := -float64(index) * (dxfDetailHeight + dxfDetailSpacing)
+		x := 0.0
+
+		writer.text(dxfAnnotationsLayer, 0, y+dxfDetailHeight+dxfTextHeight, 0, wallType.Name)
+
+		for _, layer := range wallType.Layers {
+			name := dxfLayerName(layer)
+
+			if layer.Thickness == 0 {
+				writer.line(name, x, y, x, y+dxfDetailHeight)
+			} else {
+				writer.rectangle(name, x, y, layer.Thickness, dxfDetailHeight)
+
+				if block := dxfHatchBlock(layer); block != "" {
+					writer.insert(name, block, x, y, layer.Thickness, dxfDetailHeight)
+				}
+			}
+
+			writer.text(dxfAnnotationsLayer, x+layer.Thickness/2+dxfTextHeight/2, y-dxfTextHeight, 270, dxfLayerAnnotation(layer))
+
+			x += layer.Thickness
+		}
+	}
+
+	writer.pair(0, "ENDSEC")
+	writer.pair(0, "EOF")
+
+	_, err := io.WriteString(dst, writer.builder.String())
+
+	return err
+}
+
+func sortedKeys(values map[string]bool) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func (s *Spreadsheet) ReadWallTypeDXFTo(ctx context.Context, name string, dst io.Writer) error {
+	wallType, err := s.findWallType(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if err := writeWallTypesDXF(dst, models.WallTypes{wallType}); err != nil {
+		return errors.Wrap(err, "Unable to write DXF file")
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadWallTypesDXFTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	if err := writeWallTypesDXF(dst, wallTypes); err != nil {
+		return errors.Wrap(err, "Unable to write DXF file")
+	}
+
+	return nil
+}
@@ -0,0 +1,506 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"arca3/models"
+)
+
+// orderedObject is a JSON object keeping the order of its fields, which is
+// the order of the model struct fields.
+type orderedObject []orderedField
+
+type orderedField struct {
+	key   string
+	value interface{}
+}
+
+// writeData encodes data as JSON, as YAML, or as a table with one row per
+// element of data and nested fields flattened into dotted columns, e.g.
+// "Material.Name" or "Materials.0.Thickness". Going through JSON keeps the
+// field names and the custom marshalers of the models in every format.
+func writeData(dst io.Writer, format models.Format, sheet string, data interface{}) error {
+	if format == "" || format == models.FormatJSON {
+		return json.NewEncoder(dst).Encode(data)
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	tree, err := decodeOrdered(json.NewDecoder(bytes.NewReader(encoded)))
+	if err != nil {
+		return err
+	}
+
+	if format == models.FormatYAML {
+		encoder := yaml.NewEncoder(dst)
+		encoder.SetIndent(2)
+
+		if err := encoder.Encode(yamlNode(tree)); err != nil {
+			return err
+		}
+
+		return encoder.Close()
+	}
+
+	return writeTable(dst, format, sheet, flattenRows(tree))
+}
+
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delimiter, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delimiter {
+	case '{':
+		object := orderedObject{}
+
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			object = append(object, orderedField{key: key.(string), value: value})
+		}
+
+		_, err := decoder.Token()
+
+		return object, err
+	case '[':
+		array := []interface{}{}
+
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			array = append(array, value)
+		}
+
+		_, err := decoder.Token()
+
+		return array, err
+	default:
+		return nil, errors.Errorf("unexpected JSON delimiter %v", delimiter)
+	}
+}
+
+// flattenRows makes a header and a row per element of an array, a single
+// value gives a single row.
+func flattenRows(tree interface{}) [][]interface{} {
+	elements, ok := tree.([]interface{})
+	if !ok {
+		elements = []interface{}{tree}
+	}
+
+	columns := []string{}
+	known := map[string]bool{}
+	cells := make([]map[string]interface{}, 0, len(elements))
+
+	for _, element := range elements {
+		row := map[string]interface{}{}
+
+		flattenValue("", element, func(column string, value interface{}) {
+			if !known[column] {
+				known[column] = true
+				columns = append(columns, column)
+			}
+
+			row[column] = value
+		})
+
+		cells = append(cells, row)
+	}
+
+	columns = mergeNullColumns(columns)
+
+	header := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column)
+	}
+
+	rows := [][]interface{}{header}
+
+	for _, row := range cells {
+		values := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			values = append(values, row[column])
+		}
+
+		rows = append(rows, values)
+	}
+
+	return rows
+}
+
+// mergeNullColumns drops the column of a field that is null in some rows and
+// an object or an array in others, its nested columns take its place.
+func mergeNullColumns(columns []string) []string {
+	merged := make([]string, 0, len(columns))
+	emitted := map[string]bool{}
+
+	for _, column := range columns {
+		if emitted[column] {
+			continue
+		}
+
+		nested := false
+
+		for _, other := range columns {
+			if !strings.HasPrefix(other, column+".") {
+				continue
+			}
+
+			nested = true
+
+			if !emitted[other] {
+				emitted[other] = true
+				merged = append(merged, other)
+			}
+		}
+
+		if !nested {
+			emitted[column] = true
+			merged = append(merged, column)
+		}
+	}
+
+	return merged
+}
+
+func flattenValue(prefix string, value interface{}, set func(column string, value interface{})) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + "." + key
+	}
+
+	switch typed := value.(type) {
+	case orderedObject:
+		for _, field := range typed {
+			flattenValue(join(field.key), field.value, set)
+		}
+	case []interface{}:
+		for index, element := range typed {
+			flattenValue(join(strconv.Itoa(index)), element, set)
+		}
+	default:
+		if prefix != "" {
+			set(prefix, typed)
+		}
+	}
+}
+
+func yamlNode(value interface{}) *yaml.Node {
+	switch typed := value.(type) {
+	case orderedObject:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, field := range typed {
+			node.Content = append(node.Content, yamlNode(field.key), yamlNode(field.value))
+		}
+
+		return node
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, element := range typed {
+			node.Content = append(node.Content, yamlNode(element))
+		}
+
+		return node
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: typed}
+	case float64:
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: strconv.FormatFloat(typed, 'f', -1, 64)}
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(typed)}
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+	}
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// readData decodes a JSON array, or a CSV or XLSX table written like
+// writeData does, into dst, a pointer to a slice. Table cells are converted
+// following the type of the field their dotted header points to and every row
+// goes through encoding/json, so tables get the same decoding as JSON bodies.
+// Rows are numbered like in a spreadsheet, the header being row 1.
+func readData(src io.Reader, format models.Format, dst interface{}) error {
+	if format == "" || format == models.FormatJSON {
+		return decodeRecord(src, dst)
+	}
+
+	if format != models.FormatCSV && format != models.FormatXLSX {
+		return errors.Wrapf(models.ErrInvalid, "format %s is not supported for uploads", format)
+	}
+
+	rows, err := readTable(src, format)
+	if err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	slice := reflect.ValueOf(dst).Elem()
+	elementType := slice.Type().Elem()
+
+	header := make([][]string, 0, len(rows[0]))
+	for _, column := range rows[0] {
+		header = append(header, strings.Split(strings.TrimSpace(column), "."))
+	}
+
+	for index, row := range rows[1:] {
+		if isEmptyRow(row) {
+			continue
+		}
+
+		node := map[string]interface{}{}
+
+		for column, cell := range row {
+			if column >= len(header) || header[column][0] == "" || strings.TrimSpace(cell) == "" {
+				continue
+			}
+
+			if err := setTableCell(node, elementType, header[column], strings.TrimSpace(cell), len(header)); err != nil {
+				return errors.Wrapf(err, "row %v, column %s", index+2, rows[0][column])
+			}
+		}
+
+		encoded, err := json.Marshal(tableJSONValue(node, elementType))
+		if err != nil {
+			return errors.Wrapf(err, "row %v", index+2)
+		}
+
+		element := reflect.New(elementType)
+		if err := json.Unmarshal(encoded, element.Interface()); err != nil {
+			if !errors.Is(err, models.ErrInvalid) {
+				err = errors.Wrap(models.ErrInvalid, err.Error())
+			}
+
+			return errors.Wrapf(err, "row %v", index+2)
+		}
+
+		slice.Set(reflect.Append(slice, element.Elem()))
+	}
+
+	return nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func derefType(valueType reflect.Type) reflect.Type {
+	for valueType.Kind() == reflect.Ptr {
+		valueType = valueType.Elem()
+	}
+
+	return valueType
+}
+
+func isJSONUnmarshaler(valueType reflect.Type) bool {
+	return valueType.Implements(jsonUnmarshalerType) || reflect.PointerTo(valueType).Implements(jsonUnmarshalerType)
+}
+
+// setTableCell stores the cell at path in node, converted to what the field
+// of valueType at path expects. Every element of a slice takes at least one
+// column, so slice indexes are below columns.
+func setTableCell(node map[string]interface{}, valueType reflect.Type, path []string, cell string, columns int) error {
+	valueType = derefType(valueType)
+
+	// The nested fields of types decoding themselves, like the components of
+	// a color, can't be typed and are guessed.
+	if isJSONUnmarshaler(valueType) {
+		return setNestedCell(node, path, guessTableValue(cell))
+	}
+
+	key, fieldType, err := tableField(valueType, path[0], columns)
+	if err != nil {
+		return err
+	}
+
+	if len(path) == 1 {
+		if isJSONUnmarshaler(derefType(fieldType)) {
+			node[key] = cell
+
+			return nil
+		}
+
+		value, err := convertTableCell(derefType(fieldType), cell)
+		if err != nil {
+			return err
+		}
+
+		node[key] = value
+
+		return nil
+	}
+
+	child, ok := node[key].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		node[key] = child
+	}
+
+	return setTableCell(child, fieldType, path[1:], cell, columns)
+}
+
+func setNestedCell(node map[string]interface{}, path []string, value interface{}) error {
+	for _, key := range path[:len(path)-1] {
+		child, ok := node[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			node[key] = child
+		}
+
+		node = child
+	}
+
+	node[path[len(path)-1]] = value
+
+	return nil
+}
+
+// tableField resolves one segment of a header: a struct field, matched
+// without case, a map key or a slice index below columns.
+func tableField(valueType reflect.Type, name string, columns int) (string, reflect.Type, error) {
+	switch valueType.Kind() {
+	case reflect.Struct:
+		for index := 0; index < valueType.NumField(); index++ {
+			field := valueType.Field(index)
+			if field.IsExported() && strings.EqualFold(field.Name, name) {
+				return field.Name, field.Type, nil
+			}
+		}
+
+		return "", nil, errors.Wrapf(models.ErrInvalid, "unknown field %s", name)
+	case reflect.Map:
+		return name, valueType.Elem(), nil
+	case reflect.Slice:
+		index, err := strconv.Atoi(name)
+		if err != nil || index < 0 {
+			return "", nil, errors.Wrapf(models.ErrInvalid, "%s is not an index", name)
+		}
+
+		if index >= columns {
+			return "", nil, errors.Wrapf(models.ErrInvalid, "index %s out of range for %v columns", name, columns)
+		}
+
+		return strconv.Itoa(index), valueType.Elem(), nil
+	default:
+		return "", nil, errors.Wrapf(models.ErrInvalid, "field %s is not an object", name)
+	}
+}
+
+func convertTableCell(valueType reflect.Type, cell string) (interface{}, error) {
+	switch valueType.Kind() {
+	case reflect.String, reflect.Interface:
+		return cell, nil
+	case reflect.Bool:
+		value, err := strconv.ParseBool(strings.ToLower(cell))
+		if err != nil {
+			return nil, errors.Wrapf(models.ErrInvalid, "%q is not a boolean", cell)
+		}
+
+		return value, nil
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseFloat(strings.ReplaceAll(cell, ",", "."), 64)
+		if err != nil {
+			return nil, errors.Wrapf(models.ErrInvalid, "%q is not a number", cell)
+		}
+
+		return value, nil
+	default:
+		return nil, errors.Wrapf(models.ErrInvalid, "a %s can't be set from a single column", valueType.Kind())
+	}
+}
+
+func guessTableValue(cell string) interface{} {
+	if value, err := strconv.ParseFloat(cell, 64); err == nil {
+		return value
+	}
+
+	return cell
+}
+
+// tableJSONValue turns the nodes standing for slices into arrays, their keys
+// being the indexes.
+func tableJSONValue(value interface{}, valueType reflect.Type) interface{} {
+	node, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+
+	valueType = derefType(valueType)
+
+	switch {
+	case isJSONUnmarshaler(valueType):
+		return node
+	case valueType.Kind() == reflect.Slice:
+		length := 0
+		for key := range node {
+			if index, _ := strconv.Atoi(key); index+1 > length {
+				length = index + 1
+			}
+		}
+
+		array := make([]interface{}, length)
+		for key, element := range node {
+			index, _ := strconv.Atoi(key)
+			array[index] = tableJSONValue(element, valueType.Elem())
+		}
+
+		return array
+	case valueType.Kind() == reflect.Struct:
+		for key, element := range node {
+			if field, ok := valueType.FieldByName(key); ok {
+				node[key] = tableJSONValue(element, field.Type)
+			}
+		}
+
+		return node
+	case valueType.Kind() == reflect.Map:
+		for key, element := range node {
+			node[key] = tableJSONValue(element, valueType.Elem())
+		}
+
+		return node
+	default:
+		return node
+	}
+}
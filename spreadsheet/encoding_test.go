@@ -0,0 +1,260 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+var tableFormats = []models.Format{models.FormatCSV, models.FormatXLSX}
+
+func stringPtr(value string) *string {
+	return &value
+}
+
+func floatPtr(value float64) *float64 {
+	return &value
+}
+
+func testMaterials() models.WallMaterials {
+	return models.WallMaterials{
+		{
+			Thickness:    200,
+			Function:     models.FunctionStructure,
+			IsStructural: true,
+			Material: &models.Material{
+				Name:                      stringPtr("Concrete, cast in place"),
+				MaterialCategory:          stringPtr("Concrete"),
+				CutBackgroundPatternColor: &models.Color{R: 128, G: 128, B: 128},
+				CutBackgroundPatternId:    stringPtr("Concrete"),
+				Description:               stringPtr(`Reinforced "C30/37"`),
+				Conductivity:              floatPtr(2.3),
+				Density:                   floatPtr(2400),
+				UnitPrice:                 floatPtr(120.5),
+				Unit:                      stringPtr("m3"),
+				Currency:                  stringPtr("EUR"),
+				FireRating:                floatPtr(120),
+			},
+		},
+		{
+			Thickness: 12.5,
+			Function:  models.FunctionFinish1,
+			Material: &models.Material{
+				Name:                          stringPtr("Plasterboard"),
+				SurfaceForegroundPatternColor: &models.Color{R: 255, G: 255, B: 255},
+				AcousticRating:                floatPtr(35),
+			},
+		},
+	}
+}
+
+func testAreas() models.Areas {
+	return models.Areas{
+		{
+			Name:       "Office 1/2",
+			Type:       models.AreaInterior,
+			Level:      stringPtr("L1"),
+			Building:   stringPtr("A"),
+			Properties: map[string]string{"Acoustic": "high"},
+		},
+		{
+			Name: "Outside",
+			Type: models.AreaExterior,
+		},
+	}
+}
+
+func testAreasRelations() models.AreasRelations {
+	areas := testAreas()
+	materials := testMaterials()
+
+	return models.AreasRelations{
+		{
+			AreaInternal:   areas[0],
+			AreaExternal:   areas[1],
+			Central:        materials[0],
+			WallKeynote:    stringPtr("W-01"),
+			WallArea:       floatPtr(42.5),
+			FireRating:     floatPtr(60),
+			AcousticRating: floatPtr(45),
+		},
+		{
+			AreaInternal: areas[0],
+			SameArea:     true,
+		},
+		{
+			AreaInternal: areas[1],
+			Central:      materials[1],
+		},
+	}
+}
+
+// assertSameRows compares what the upload would write to the spreadsheet.
+func assertSameRows(t *testing.T, want, got []*sheets.RowData) {
+	t.Helper()
+
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(wantJSON, gotJSON) {
+		t.Errorf("rows differ\nwant %s\ngot  %s", wantJSON, gotJSON)
+	}
+}
+
+func TestMaterialsRoundTrip(t *testing.T) {
+	materials := testMaterials()
+
+	want := []*sheets.RowData{}
+	for _, material := range materials {
+		want = append(want, materialRow(material, models.UnitCentimeter))
+	}
+
+	for _, format := range tableFormats {
+		t.Run(string(format), func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writeData(&buffer, format, "Materials", convertWallMaterials(materials, models.UnitMillimeter)); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, flat, err := decodeMaterials(&buffer, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if flat {
+				t.Error("materials read as the flat layout")
+			}
+
+			got := []*sheets.RowData{}
+			for _, material := range decoded {
+				got = append(got, materialRow(material, models.UnitCentimeter))
+			}
+
+			assertSameRows(t, want, got)
+		})
+	}
+}
+
+func TestAreasRoundTrip(t *testing.T) {
+	areas := testAreas()
+
+	want := []*sheets.RowData{}
+	for _, area := range areas {
+		want = append(want, areaRow(area))
+	}
+
+	for _, format := range tableFormats {
+		t.Run(string(format), func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writeData(&buffer, format, "Areas", areas); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := decodeAreas(&buffer, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []*sheets.RowData{}
+			for _, area := range decoded {
+				got = append(got, areaRow(area))
+			}
+
+			assertSameRows(t, want, got)
+		})
+	}
+}
+
+func TestDecodeAreasDuplicate(t *testing.T) {
+	body := "Name,Type\nOffice,interior\nOffice,exterior\n"
+
+	if _, err := decodeAreas(strings.NewReader(body), models.FormatCSV); !errors.Is(err, models.ErrInvalid) {
+		t.Errorf("got %v, want an ErrInvalid for a duplicate area", err)
+	}
+}
+
+func TestAreasRelationsRoundTrip(t *testing.T) {
+	relations := testAreasRelations()
+
+	want := []*sheets.RowData{}
+	for _, relation := range relations {
+		want = append(want, relationRow(relation))
+	}
+
+	for _, format := range tableFormats {
+		t.Run(string(format), func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := writeData(&buffer, format, "Areas relations", convertAreasRelations(relations, models.UnitMillimeter)); err != nil {
+				t.Fatal(err)
+			}
+
+			decoded, err := decodeAreasRelations(&buffer, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []*sheets.RowData{}
+			for _, relation := range decoded {
+				got = append(got, relationRow(relation))
+			}
+
+			assertSameRows(t, want, got)
+		})
+	}
+}
+
+func TestReadDataInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		format models.Format
+		body   string
+	}{
+		{"malformed JSON", models.FormatJSON, `[{"Name": `},
+		{"wrong JSON type", models.FormatJSON, `{"Name": "Office"}`},
+		{"unknown column", models.FormatCSV, "Name,Color\nOffice,red\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var areas models.Areas
+
+			err := readData(strings.NewReader(test.body), test.format, &areas)
+			if !errors.Is(err, models.ErrInvalid) {
+				t.Errorf("got %v, want an ErrInvalid", err)
+			}
+		})
+	}
+}
+
+func TestReadDataIndexCap(t *testing.T) {
+	var wallTypes models.WallTypes
+
+	err := readData(strings.NewReader("Name,Layers.1000000000.Thickness\nWT-01,200\n"), models.FormatCSV, &wallTypes)
+	if !errors.Is(err, models.ErrInvalid) {
+		t.Errorf("got %v, want an ErrInvalid", err)
+	}
+
+	wallTypes = nil
+
+	err = readData(strings.NewReader("Name,Layers.0.Thickness,Layers.1.Thickness\nWT-01,200,12.5\n"), models.FormatCSV, &wallTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(wallTypes) != 1 || len(wallTypes[0].Layers) != 2 || wallTypes[0].Layers[1].Thickness != 12.5 {
+		t.Errorf("unexpected wall types %+v", wallTypes)
+	}
+}
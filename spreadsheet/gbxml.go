@@ -0,0 +1,209 @@
+package spreadsheet
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+type gbXMLDocument struct {
+	XMLName              xml.Name             `xml:"gbXML"`
+	Xmlns                string               `xml:"xmlns,attr"`
+	Version              string               `xml:"version,attr"`
+	TemperatureUnit      string               `xml:"temperatureUnit,attr"`
+	LengthUnit           string               `xml:"lengthUnit,attr"`
+	AreaUnit             string               `xml:"areaUnit,attr"`
+	VolumeUnit           string               `xml:"volumeUnit,attr"`
+	UseSIUnitsForResults bool                 `xml:"useSIUnitsForResults,attr"`
+	Constructions        []*gbXMLConstruction `xml:"Construction"`
+	Layers               []*gbXMLLayer        `xml:"Layer"`
+	Materials            []*gbXMLMaterial     `xml:"Material"`
+}
+
+type gbXMLConstruction struct {
+	ID      string        `xml:"id,attr"`
+	UValue  *gbXMLValue   `xml:"U-value,omitempty"`
+	LayerID gbXMLLayerRef `xml:"LayerId"`
+	Name    string        `xml:"Name"`
+}
+
+type gbXMLLayerRef struct {
+	LayerIDRef string `xml:"layerIdRef,attr"`
+}
+
+type gbXMLLayer struct {
+	ID          string             `xml:"id,attr"`
+	MaterialIDs []gbXMLMaterialRef `xml:"MaterialId"`
+	Name        string             `xml:"Name"`
+}
+
+type gbXMLMaterialRef struct {
+	MaterialIDRef string `xml:"materialIdRef,attr"`
+}
+
+type gbXMLMaterial struct {
+	ID           string      `xml:"id,attr"`
+	Name         string      `xml:"Name"`
+	Description  string      `xml:"Description,omitempty"`
+	RValue       *gbXMLValue `xml:"R-value,omitempty"`
+	Thickness    *gbXMLValue `xml:"Thickness"`
+	Conductivity *gbXMLValue `xml:"Conductivity,omitempty"`
+	Density      *gbXMLValue `xml:"Density,omitempty"`
+	SpecificHeat *gbXMLValue `xml:"SpecificHeat,omitempty"`
+}
+
+type gbXMLValue struct {
+	Unit  string `xml:"unit,attr"`
+	Value string `xml:",chardata"`
+}
+
+func newGBXMLValue(unit string, value float64) *gbXMLValue {
+	return &gbXMLValue{Unit: unit, Value: strconv.FormatFloat(value, 'f', -1, 64)}
+}
+
+func newOptionalGBXMLValue(unit string, value *float64) *gbXMLValue {
+	if value == nil {
+		return nil
+	}
+
+	return newGBXMLValue(unit, *value)
+}
+
+// gbXMLID builds an xsd:ID from a readable prefix and name, the short hash
+// keeps apart names that only differ in the characters being replaced. Ids
+// only depend on names and thicknesses so they stay the same across exports.
+func gbXMLID(prefix, name string) string {
+	var builder strings.Builder
+
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			builder.WriteRune(r)
+		default:
+			builder.WriteRune('_')
+		}
+	}
+
+	sum := sha1.Sum([]byte(name))
+
+	return prefix + "-" + strings.Trim(builder.String(), "_") + "-" + hex.EncodeToString(sum[:])[:6]
+}
+
+// buildGBXML writes one Construction and one Layer per wall type. gbXML
+// materials carry their thickness, so a material used at two thicknesses
+// gives two Material elements. The U-value is the one of /wall_types/thermal,
+// surface resistances included, taking the exterior ones when any assembly of
+// the type faces the exterior, and is left out when a layer lacks conductivity.
+func buildGBXML(wallTypes models.WallTypes, assemblies models.WallAssemblies) *gbXMLDocument {
+	document := &gbXMLDocument{
+		Xmlns:                "http://www.gbxml.org/schema",
+		Version:              "6.01",
+		TemperatureUnit:      "C",
+		LengthUnit:           "Meters",
+		AreaUnit:             "SquareMeters",
+		VolumeUnit:           "CubicMeters",
+		UseSIUnitsForResults: true,
+	}
+
+	materials := map[string]bool{}
+
+	exterior := map[string]bool{}
+	for _, assembly := range assemblies {
+		if assembly.Exterior {
+			exterior[assembly.WallType] = true
+		}
+	}
+
+	for _, wallType := range wallTypes {
+		if len(wallType.Layers) == 0 {
+			continue
+		}
+
+		layer := &gbXMLLayer{
+			ID:   "layer-" + wallType.Fingerprint,
+			Name: wallType.Name,
+		}
+
+		for _, wallLayer := range wallType.Layers {
+			name := layerMaterialName(wallLayer)
+			thickness := wallLayer.Thickness / 1000
+			id := gbXMLID("material", name+"|"+strconv.FormatFloat(wallLayer.Thickness, 'f', -1, 64))
+
+			layer.MaterialIDs = append(layer.MaterialIDs, gbXMLMaterialRef{MaterialIDRef: id})
+
+			material := &gbXMLMaterial{
+				ID:        id,
+				Name:      name,
+				Thickness: newGBXMLValue("Meters", thickness),
+			}
+
+			if wallLayer.Material != nil {
+				if wallLayer.Material.Description != nil {
+					material.Description = *wallLayer.Material.Description
+				}
+
+				material.Conductivity = newOptionalGBXMLValue("WPerMeterK", wallLayer.Material.Conductivity)
+				material.Density = newOptionalGBXMLValue("KgPerCubicM", wallLayer.Material.Density)
+				material.SpecificHeat = newOptionalGBXMLValue("JPerKgK", wallLayer.Material.SpecificHeat)
+
+				if conductivity := wallLayer.Material.Conductivity; conductivity != nil && *conductivity > 0 {
+					material.RValue = newGBXMLValue("SquareMeterKPerW", thickness / *conductivity)
+				}
+			}
+
+			if !materials[id] {
+				materials[id] = true
+				document.Materials = append(document.Materials, material)
+			}
+		}
+
+		construction := &gbXMLConstruction{
+			ID:      "construction-" + wallType.Fingerprint,
+			LayerID: gbXMLLayerRef{LayerIDRef: layer.ID},
+			Name:    wallType.Name,
+		}
+
+		thermal := computeWallThermal(&models.WallAssembly{
+			WallType: wallType.Name,
+			Exterior: exterior[wallType.Name],
+			Layers:   wallType.Layers,
+		}, 0)
+
+		if thermal.Complete {
+			construction.UValue = newGBXMLValue("WPerSquareMeterK", *thermal.UValue)
+		}
+
+		document.Constructions = append(document.Constructions, construction)
+		document.Layers = append(document.Layers, layer)
+	}
+
+	return document
+}
+
+func (s *Spreadsheet) ReadWallTypesGBXMLTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	if _, err := io.WriteString(dst, xml.Header); err != nil {
+		return errors.Wrap(err, "Unable to write gbXML file")
+	}
+
+	encoder := xml.NewEncoder(dst)
+	encoder.Indent("", "  ")
+
+	if err := encoder.Encode(buildGBXML(wallTypes, assemblies)); err != nil {
+		return errors.Wrap(err, "Unable to write gbXML file")
+	}
+
+	return nil
+}
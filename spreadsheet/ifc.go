@@ -0,0 +1,240 @@
+package spreadsheet
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+const (
+	ifcGuidAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz_$"
+)
+
+// ifcLayerPriorities follows the Revit function order, IFC priorities go
+// from 0 to 100 and the highest wins.
+var ifcLayerPriorities = map[models.MaterialFunction]int{
+	models.FunctionStructure:  100,
+	models.FunctionSubstrate:  80,
+	models.FunctionInsulation: 60,
+	models.FunctionFinish1:    40,
+	models.FunctionFinish2:    20,
+	models.FunctionMembrane:   0,
+}
+
+// ifcWriter numbers the entities of a STEP physical file as they are added.
+type ifcWriter struct {
+	builder strings.Builder
+	next    int
+}
+
+func (w *ifcWriter) add(format string, args ...interface{}) string {
+	w.next++
+	reference := "#" + strconv.Itoa(w.next)
+
+	fmt.Fprintf(&w.builder, "%s=%s;\n", reference, fmt.Sprintf(format, args...))
+
+	return reference
+}
+
+// ifcGuid derives a stable IfcGloballyUniqueId from seed, so exporting twice
+// the same wall types gives the same ids.
+func ifcGuid(seed string) string {
+	sum := sha1.Sum([]byte(seed))
+	number := new(big.Int).SetBytes(sum[:16])
+
+	guid := make([]byte, 22)
+	base := big.NewInt(64)
+	digit := new(big.Int)
+
+	for index := len(guid) - 1; index >= 0; index-- {
+		number.DivMod(number, base, digit)
+		guid[index] = ifcGuidAlphabet[digit.Int64()]
+	}
+
+	return string(guid)
+}
+
+// ifcString encodes a STEP string, quotes and backslashes are doubled and
+// anything outside printable ASCII goes through the \X2\ escape.
+func ifcString(value string) string {
+	var builder strings.Builder
+
+	builder.WriteString("'")
+
+	for _, r := range value {
+		switch {
+		case r == '\'':
+			builder.WriteString("''")
+		case r == '\\':
+			builder.WriteString(`\\`)
+		case r >= 0x20 && r < 0x7f:
+			builder.WriteRune(r)
+		case r <= 0xffff:
+			fmt.Fprintf(&builder, `\X2\%04X\X0\`, r)
+		default:
+			fmt.Fprintf(&builder, `\X4\%08X\X0\`, r)
+		}
+	}
+
+	builder.WriteString("'")
+
+	return builder.String()
+}
+
+func ifcOptionalString(value *string) string {
+	if value == nil || *value == "" {
+		return "$"
+	}
+
+	return ifcString(*value)
+}
+
+// ifcReal always writes a decimal point, STEP reads "200" as an integer.
+func ifcReal(value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(formatted, ".") {
+		formatted += "."
+	}
+
+	return formatted
+}
+
+// ifcLayerCategory uses the categories recommended by IFC4 for the layers
+// they cover, finishes are inner or outer depending on their side of the core.
+func ifcLayerCategory(layer *models.WallLayer, beforeCore bool) string {
+	switch layer.Function {
+	case models.FunctionStructure:
+		return "LoadBearing"
+	case models.FunctionInsulation:
+		return "Insulation"
+	case models.FunctionFinish1, models.FunctionFinish2:
+		if beforeCore {
+			return "Outer finish"
+		}
+
+		return "Inner finish"
+	default:
+		return layer.Function.String()
+	}
+}
+
+func ifcIsVentilated(layer *models.WallLayer) string {
+	if layer.Material != nil &&
+		layer.Material.MaterialCategory != nil &&
+		strings.EqualFold(*layer.Material.MaterialCategory, "air") {
+		return ".T."
+	}
+
+	return ".F."
+}
+
+// writeWallTypesIFC writes an IFC4 file declaring one IfcWallType per wall
+// type, each associated to an IfcMaterialLayerSet whose layers go from the
+// exterior to the interior face. Lengths are in millimeters.
+func writeWallTypesIFC(dst io.Writer, wallTypes models.WallTypes, now time.Time) error {
+	writer := &ifcWriter{}
+
+	units := writer.add("IFCUNITASSIGNMENT((%s))", writer.add("IFCSIUNIT(*,.LENGTHUNIT.,.MILLI.,.METRE.)"))
+	origin := writer.add("IFCAXIS2PLACEMENT3D(%s,$,$)", writer.add("IFCCARTESIANPOINT((0.,0.,0.))"))
+	representationContext := writer.add("IFCGEOMETRICREPRESENTATIONCONTEXT($,'Model',3,1.E-05,%s,$)", origin)
+	project := writer.add("IFCPROJECT(%s,$,'Wall types',$,$,$,$,(%s),%s)", ifcString(ifcGuid("project")), representationContext, units)
+
+	materials := map[string]string{}
+	wallTypeReferences := []string{}
+
+	for _, wallType := range wallTypes {
+		if len(wallType.Layers) == 0 {
+			continue
+		}
+
+		first, _, _ := coreBoundaries(wallType.Layers)
+		layers := []string{}
+
+		for index, layer := range wallType.Layers {
+			name := layerMaterialName(layer)
+
+			material, ok := materials[name]
+			if !ok {
+				var category, description *string
+				if layer.Material != nil {
+					category = layer.Material.MaterialCategory
+					description = layer.Material.Description
+				}
+
+				material = writer.add("IFCMATERIAL(%s,%s,%s)", ifcString(name), ifcOptionalString(description), ifcOptionalString(category))
+				materials[name] = material
+			}
+
+			layers = append(layers, writer.add("IFCMATERIALLAYER(%s,%s,%s,%s,$,%s,%d)",
+				material,
+				ifcReal(layer.Thickness),
+				ifcIsVentilated(layer),
+				ifcString(name),
+				ifcString(ifcLayerCategory(layer, index < first)),
+				ifcLayerPriorities[layer.Function],
+			))
+		}
+
+		layerSet := writer.add("IFCMATERIALLAYERSET((%s),%s,$)", strings.Join(layers, ","), ifcString(wallType.Name))
+
+		reference := writer.add("IFCWALLTYPE(%s,$,%s,$,$,$,$,%s,$,.NOTDEFINED.)",
+			ifcString(ifcGuid("wall type "+wallType.Fingerprint)),
+			ifcString(wallType.Name),
+			ifcString(wallType.Fingerprint),
+		)
+		wallTypeReferences = append(wallTypeReferences, reference)
+
+		writer.add("IFCRELASSOCIATESMATERIAL(%s,$,$,$,(%s),%s)",
+			ifcString(ifcGuid("material "+wallType.Fingerprint)),
+			reference,
+			layerSet,
+		)
+	}
+
+	if len(wallTypeReferences) > 0 {
+		writer.add("IFCRELDECLARES(%s,$,$,$,%s,(%s))",
+			ifcString(ifcGuid("declares")),
+			project,
+			strings.Join(wallTypeReferences, ","),
+		)
+	}
+
+	header := strings.Join([]string{
+		"ISO-10303-21;",
+		"HEADER;",
+		"FILE_DESCRIPTION(('ViewDefinition [DesignTransferView]'),'2;1');",
+		fmt.Sprintf("FILE_NAME('wall_types.ifc','%s',(''),(''),'arca3','arca3','');", now.UTC().Format("2006-01-02T15:04:05")),
+		"FILE_SCHEMA(('IFC4'));",
+		"ENDSEC;",
+		"DATA;",
+		"",
+	}, "\n")
+
+	if _, err := io.WriteString(dst, header+writer.builder.String()+"ENDSEC;\nEND-ISO-10303-21;\n"); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadWallTypesIFCTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	if err := writeWallTypesIFC(dst, wallTypes, time.Now()); err != nil {
+		return errors.Wrap(err, "Unable to write IFC file")
+	}
+
+	return nil
+}
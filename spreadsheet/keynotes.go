@@ -0,0 +1,209 @@
+package spreadsheet
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+const (
+	keynoteMaterialsGroup = "Materials"
+	keynoteWallsGroup     = "Walls"
+)
+
+// buildKeynotes groups material keynotes under their material category and
+// wall keynotes under a Walls group. A key is only listed once, the first
+// material or wall type using it gives its text.
+func buildKeynotes(materials models.WallMaterials, wallTypes models.WallTypes) models.KeynoteEntries {
+	entries := models.KeynoteEntries{}
+	keys := map[string]bool{}
+
+	add := func(key, text, parent string) {
+		if key == "" || keys[key] {
+			return
+		}
+
+		keys[key] = true
+		entries = append(entries, &models.KeynoteEntry{Key: key, Text: text, Parent: parent})
+	}
+
+	for _, material := range materials {
+		if material.Material == nil || material.Material.Keynote == nil || *material.Material.Keynote == "" {
+			continue
+		}
+
+		parent := keynoteMaterialsGroup
+		if material.Material.MaterialCategory != nil && *material.Material.MaterialCategory != "" {
+			parent = *material.Material.MaterialCategory
+		}
+
+		add(parent, parent, "")
+
+		text := ""
+		if material.Material.Name != nil {
+			text = *material.Material.Name
+		}
+
+		if material.Material.Description != nil && *material.Material.Description != "" {
+			text = *material.Material.Description
+		}
+
+		add(*material.Material.Keynote, text, parent)
+	}
+
+	for _, wallType := range wallTypes {
+		for _, relation := range wallType.Relations {
+			if relation.WallKeynote == nil || *relation.WallKeynote == "" {
+				continue
+			}
+
+			add(keynoteWallsGroup, keynoteWallsGroup, "")
+			add(*relation.WallKeynote, wallType.Name, keynoteWallsGroup)
+		}
+	}
+
+	return entries
+}
+
+func writeKeynotes(dst io.Writer, entries models.KeynoteEntries) error {
+	clean := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+
+	var builder strings.Builder
+	for _, entry := range entries {
+		builder.WriteString(clean.Replace(entry.Key))
+		builder.WriteString("\t")
+		builder.WriteString(clean.Replace(entry.Text))
+
+		if entry.Parent != "" {
+			builder.WriteString("\t")
+			builder.WriteString(clean.Replace(entry.Parent))
+		}
+
+		builder.WriteString("\r\n")
+	}
+
+	_, err := io.WriteString(dst, builder.String())
+
+	return err
+}
+
+// readKeynotes parses a Revit keynote file: one key, text and optional
+// parent per line, separated by tabs.
+func readKeynotes(src io.Reader) (models.KeynoteEntries, error) {
+	entries := models.KeynoteEntries{}
+
+	scanner := bufio.NewScanner(src)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\uFEFF"), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) < 2 {
+			return nil, errors.Wrapf(models.ErrInvalid, "line %v: expected key and text separated by a tab", line)
+		}
+
+		entry := &models.KeynoteEntry{
+			Key:  strings.TrimSpace(fields[0]),
+			Text: strings.TrimSpace(fields[1]),
+		}
+
+		if len(fields) > 2 {
+			entry.Parent = strings.TrimSpace(fields[2])
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Unable to read keynote file")
+	}
+
+	return entries, nil
+}
+
+func validateKeynotes(entries models.KeynoteEntries, materials models.WallMaterials, relations models.AreasRelations) *models.KeynotesValidation {
+	validation := &models.KeynotesValidation{
+		Entries:         len(entries),
+		MissingKeynotes: []*models.MissingKeynote{},
+		UnknownParents:  models.KeynoteEntries{},
+	}
+
+	keys := map[string]bool{}
+	for _, entry := range entries {
+		keys[entry.Key] = true
+	}
+
+	for _, entry := range entries {
+		if entry.Parent != "" && !keys[entry.Parent] {
+			validation.UnknownParents = append(validation.UnknownParents, entry)
+		}
+	}
+
+	for _, material := range materials {
+		if material.Material == nil || material.Material.Keynote == nil || *material.Material.Keynote == "" {
+			continue
+		}
+
+		if !keys[*material.Material.Keynote] {
+			validation.MissingKeynotes = append(validation.MissingKeynotes, &models.MissingKeynote{
+				Keynote: *material.Material.Keynote,
+				Source:  "material " + *material.Material.Name,
+			})
+		}
+	}
+
+	for _, relation := range relations {
+		if relation.WallKeynote == nil || *relation.WallKeynote == "" {
+			continue
+		}
+
+		if !keys[*relation.WallKeynote] {
+			validation.MissingKeynotes = append(validation.MissingKeynotes, &models.MissingKeynote{
+				Keynote: *relation.WallKeynote,
+				Source:  "relation " + relationLabel(relation),
+			})
+		}
+	}
+
+	return validation
+}
+
+func (s *Spreadsheet) ReadKeynotesTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	if err := writeKeynotes(dst, buildKeynotes(s.materials, wallTypes)); err != nil {
+		return errors.Wrap(err, "Unable to write keynote file")
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ValidateKeynotesFrom(ctx context.Context, src io.Reader, dst io.Writer) error {
+	entries, err := readKeynotes(src)
+	if err != nil {
+		return errors.Wrap(err, "Unable to read keynote file")
+	}
+
+	if s.relations == nil {
+		if err := s.getAreasRelations(ctx); err != nil {
+			return errors.Wrap(err, "Unable to read areas relations from spreadsheet")
+		}
+	}
+
+	if err := json.NewEncoder(dst).Encode(validateKeynotes(entries, s.materials, s.relations)); err != nil {
+		return errors.Wrap(err, "Unable to encode keynotes validation to JSON")
+	}
+
+	return nil
+}
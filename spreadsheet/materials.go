@@ -1,10 +1,13 @@
 package spreadsheet
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"io"
 	"log"
+	"regexp"
+	"strings"
 
 	"github.com/pkg/errors"
 	"google.golang.org/api/sheets/v4"
@@ -12,12 +15,74 @@ import (
 	"arca3/models"
 )
 
+var headerUnit = regexp.MustCompile(`[(\[]\s*([^)\]]+?)\s*[)\]]\s*$`)
+
+// materialsThicknessUnit reads the unit declared in the thickness header, e.g.
+// "Thickness (cm)", and falls back to the project unit, also when the header
+// ends with something else in parentheses like "Thickness (nominal)".
+func (s *Spreadsheet) materialsThicknessUnit(header []*sheets.RowData) models.LengthUnit {
+	if len(header) == 0 {
+		return s.thicknessUnit
+	}
+
+	value := readPtrStringByCellIndex(header[0], 0)
+	if value == nil {
+		return s.thicknessUnit
+	}
+
+	match := headerUnit.FindStringSubmatch(*value)
+	if match == nil {
+		return s.thicknessUnit
+	}
+
+	unit, err := models.ParseLengthUnit(match[1])
+	if err != nil {
+		return s.thicknessUnit
+	}
+
+	return unit
+}
+
+// patternColorColumns are the pattern colors of MATERIALS. A cell that can't
+// be parsed leaves the color nil and is kept in invalidColors.
+var patternColorColumns = []struct {
+	index int
+	field string
+	color func(material *models.Material) **models.Color
+}{
+	{5, "CutBackgroundPatternColor", func(material *models.Material) **models.Color { return &material.CutBackgroundPatternColor }},
+	{7, "CutForegroundPatternColor", func(material *models.Material) **models.Color { return &material.CutForegroundPatternColor }},
+	{9, "SurfaceForegroundPatternColor", func(material *models.Material) **models.Color { return &material.SurfaceForegroundPatternColor }},
+}
+
+// checkInvalidColors refuses to write a material without one of the colors
+// whose cell couldn't be parsed, the cell would be cleared and what was typed
+// in it lost.
+func (s *Spreadsheet) checkInvalidColors(materials models.WallMaterials) error {
+	for _, invalid := range s.invalidColors {
+		for _, material := range materials {
+			if material.Material == nil || material.Material.Name == nil || *material.Material.Name != invalid.Material {
+				continue
+			}
+
+			for _, column := range patternColorColumns {
+				if column.field == invalid.Field && *column.color(material.Material) == nil {
+					return errors.Wrapf(models.ErrInvalid, "%s of material %s is %q in row %v of the sheet, fix the cell or set a color",
+						invalid.Field, invalid.Material, invalid.Value, invalid.Row)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *Spreadsheet) getMaterials(ctx context.Context) error {
-	ranges := "MATERIALS!A2:O"
+	headerRange, ranges := "MATERIALS!B1", "MATERIALS!A2:X"
 	result, err := s.client.Spreadsheets.
 		Get(s.spreadsheetID).
 		Context(ctx).
-		Ranges(ranges).
+		Ranges(headerRange, ranges).
 		Fields(effectiveValue).
 		IncludeGridData(true).
 		Do()
@@ -25,8 +90,12 @@ func (s *Spreadsheet) getMaterials(ctx context.Context) error {
 		return errors.Wrapf(err, "Unable to retrieve spreadsheet %s", ranges)
 	}
 
-	materials := make(models.WallMaterials, 0, len(result.Sheets[0].Data[0].RowData))
-	rowsFromSpreadsheet := result.Sheets[0].Data[0].RowData
+	unit := s.materialsThicknessUnit(result.Sheets[0].Data[0].RowData)
+
+	materials := make(models.WallMaterials, 0, len(result.Sheets[0].Data[1].RowData))
+	rowsFromSpreadsheet := result.Sheets[0].Data[1].RowData
+	rows := newSheetRows()
+	invalidColors := models.InvalidColors{}
 
 	for index, row := range rowsFromSpreadsheet {
 		material, err := readStringByCellIndex(row, 3)
@@ -50,114 +119,286 @@ func (s *Spreadsheet) getMaterials(ctx context.Context) error {
 			return errors.Wrapf(err, "error reading isStructural in row %v", index)
 		}
 
-		function, err := readStringByCellIndex(row, 2)
+		functionValue, err := readStringByCellIndex(row, 2)
 		if err != nil {
 			return errors.Wrapf(err, "error reading function in row %v", index)
 		}
 
+		function, err := models.ParseMaterialFunction(functionValue)
+		if err != nil {
+			return errors.Wrapf(err, "error reading function of material %s in row %v", material, index)
+		}
+
 		materials = append(materials, &models.WallMaterial{
-			Thickness:    thickness,
+			Thickness:    unit.ToMillimeters(thickness),
 			Function:     function,
 			IsStructural: isStructural,
 			Material: &models.Material{
-				Name:                          &material,
-				MaterialCategory:              readPtrStringByCellIndex(row, 4),
-				CutBackgroundPatternColor:     readPtrStringByCellIndex(row, 5),
-				CutBackgroundPatternId:        readPtrStringByCellIndex(row, 6),
-				CutForegroundPatternColor:     readPtrStringByCellIndex(row, 7),
-				CutForegroundPatternId:        readPtrStringByCellIndex(row, 8),
-				SurfaceForegroundPatternColor: readPtrStringByCellIndex(row, 9),
-				SurfaceForegroundPatternId:    readPtrStringByCellIndex(row, 10),
-				Mark:                          readPtrStringByCellIndex(row, 11),
-				Keynote:                       readPtrStringByCellIndex(row, 12),
-				Description:                   readPtrStringByCellIndex(row, 13),
-				Manufacturer:                  readPtrStringByCellIndex(row, 14),
+				Name:                       &material,
+				MaterialCategory:           readPtrStringByCellIndex(row, 4),
+				CutBackgroundPatternId:     readPtrStringByCellIndex(row, 6),
+				CutForegroundPatternId:     readPtrStringByCellIndex(row, 8),
+				SurfaceForegroundPatternId: readPtrStringByCellIndex(row, 10),
+				Mark:                       readPtrStringByCellIndex(row, 11),
+				Keynote:                    readPtrStringByCellIndex(row, 12),
+				Description:                readPtrStringByCellIndex(row, 13),
+				Manufacturer:               readPtrStringByCellIndex(row, 14),
+				Conductivity:               readPtrNumberByCellIndex(row, 15),
+				Density:                    readPtrNumberByCellIndex(row, 16),
+				SpecificHeat:               readPtrNumberByCellIndex(row, 17),
+				CarbonFactor:               readPtrNumberByCellIndex(row, 18),
+				UnitPrice:                  readPtrNumberByCellIndex(row, 19),
+				Unit:                       readPtrStringByCellIndex(row, 20),
+				Currency:                   readPtrStringByCellIndex(row, 21),
+				FireRating:                 readPtrNumberByCellIndex(row, 22),
+				AcousticRating:             readPtrNumberByCellIndex(row, 23),
 			},
 		})
+		rows.add(material, index)
+
+		for _, column := range patternColorColumns {
+			color, err := readPtrColorByCellIndex(row, column.index)
+			if err != nil {
+				log.Printf("Ignoring %s of material %s in row %v: %v", column.field, material, index, err)
+
+				invalidColors = append(invalidColors, &models.InvalidColor{
+					Material: material,
+					Field:    column.field,
+					Value:    *readPtrTextByCellIndex(row, column.index),
+					Row:      index + 2,
+					Error:    err.Error(),
+				})
+
+				continue
+			}
+
+			*column.color(materials[len(materials)-1].Material) = color
+		}
 	}
 
 	s.materials = materials
+	s.materialsUnit = unit
+	s.materialsRows = rows
+	s.invalidColors = invalidColors
 
 	return nil
 }
 
-func (s *Spreadsheet) ReadMaterialsTo(ctx context.Context, dst io.Writer) error {
+func (s *Spreadsheet) ReadMaterialsTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error {
 	if s.materials == nil {
 		if err := s.getMaterials(ctx); err != nil {
 			return errors.Wrap(err, "Unable to read materials from spreadsheet")
 		}
 	}
 
-	if err := json.NewEncoder(dst).Encode(s.materials); err != nil {
-		return errors.Wrap(err, "Unable to encode materials to JSON")
+	if err := writeData(dst, options.Format, "Materials", convertWallMaterials(s.materials, options.Units)); err != nil {
+		return errors.Wrap(err, "Unable to encode materials")
 	}
 
 	return nil
 }
 
-func (s *Spreadsheet) UploadMaterialsFrom(ctx context.Context, src io.Reader) error {
-	var areasRelations models.Materials
+// ReadInvalidColorsTo reports the pattern color cells that couldn't be parsed.
+func (s *Spreadsheet) ReadInvalidColorsTo(ctx context.Context, dst io.Writer) error {
+	if err := s.loadMaterials(ctx); err != nil {
+		return err
+	}
 
-	if err := json.NewDecoder(src).Decode(&areasRelations); err != nil {
-		return errors.Wrap(err, "Unable to decode areas relations from JSON")
+	if err := json.NewEncoder(dst).Encode(s.invalidColors); err != nil {
+		return errors.Wrap(err, "Unable to encode invalid colors to JSON")
 	}
 
-	if len(areasRelations) == 0 {
-		return errors.Wrap(models.ErrInvalid, "empty areas relations")
+	return nil
+}
+
+func (s *Spreadsheet) UploadMaterialsFrom(ctx context.Context, src io.Reader, format models.Format) error {
+	materials, flat, err := decodeMaterials(src, format)
+	if err != nil {
+		return errors.Wrap(err, "Unable to decode materials")
 	}
 
-	if err := s.uploadMaterials(ctx, areasRelations); err != nil {
-		return errors.Wrap(err, "Unable to upload areas relations to spreadsheet")
+	if err := s.uploadMaterials(ctx, materials, flat); err != nil {
+		return errors.Wrap(err, "Unable to upload materials to spreadsheet")
 	}
 
 	return nil
 }
 
-func (s *Spreadsheet) uploadMaterials(ctx context.Context, materials models.Materials) error {
-	requests := []*sheets.Request{}
+// isFlatMaterials tells the Materials layout, uploaded before thicknesses and
+// functions could be, from the WallMaterials layout read by ReadMaterialsTo by
+// the Material field only the latter has.
+func isFlatMaterials(data []byte, format models.Format) bool {
+	keys := []string{}
+
+	switch format {
+	case models.FormatCSV, models.FormatXLSX:
+		rows, err := readTable(bytes.NewReader(data), format)
+		if err != nil || len(rows) == 0 {
+			return false
+		}
+
+		keys = rows[0]
+	default:
+		var elements []map[string]json.RawMessage
+		if err := json.Unmarshal(data, &elements); err != nil {
+			return false
+		}
+
+		for _, element := range elements {
+			for key := range element {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	if len(keys) == 0 {
+		return false
+	}
+
+	for _, key := range keys {
+		if strings.EqualFold(strings.TrimSpace(strings.SplitN(key, ".", 2)[0]), "Material") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeMaterials reads the layout written by ReadMaterialsTo, thicknesses
+// in millimeters, and rejects the rows getMaterials couldn't read back. The
+// flat Materials layout is still accepted, flat telling that the thickness,
+// function and structural flag are to be taken from the sheet.
+func decodeMaterials(src io.Reader, format models.Format) (models.WallMaterials, bool, error) {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var materials models.WallMaterials
 
+	flat := isFlatMaterials(data, format)
+	if flat {
+		var flatMaterials models.Materials
+
+		if err := readData(bytes.NewReader(data), format, &flatMaterials); err != nil {
+			return nil, false, err
+		}
+
+		for _, material := range flatMaterials {
+			if material != nil {
+				materials = append(materials, &models.WallMaterial{Material: material})
+			} else {
+				materials = append(materials, nil)
+			}
+		}
+	} else if err := readData(bytes.NewReader(data), format, &materials); err != nil {
+		return nil, false, err
+	}
+
+	if len(materials) == 0 {
+		return nil, false, errors.Wrap(models.ErrInvalid, "empty materials")
+	}
+
+	names := map[string]bool{}
 	for index, material := range materials {
-		requests = append(requests, &sheets.Request{
-			UpdateCells: &sheets.UpdateCellsRequest{
-				Fields: "*",
-				Range: &sheets.GridRange{
-					SheetId:          1466546092,
-					StartRowIndex:    int64(index) + 1,
-					EndRowIndex:      int64(index) + 2,
-					StartColumnIndex: 0,
-					EndColumnIndex:   12,
-				},
-				Rows: []*sheets.RowData{
-					{
-						Values: []*sheets.CellData{
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.Name}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.MaterialCategory}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.CutBackgroundPatternColor}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.CutBackgroundPatternId}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.CutForegroundPatternColor}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.CutForegroundPatternId}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.SurfaceForegroundPatternColor}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.SurfaceForegroundPatternId}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.Mark}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.Keynote}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.Description}},
-							{UserEnteredValue: &sheets.ExtendedValue{StringValue: material.Manufacturer}},
-						},
-					},
-				},
-			},
-		})
+		if material == nil || material.Material == nil || material.Material.Name == nil || *material.Material.Name == "" {
+			return nil, false, errors.Wrapf(models.ErrInvalid, "material %v has no name", index)
+		}
+
+		name := *material.Material.Name
+		if names[name] {
+			return nil, false, errors.Wrapf(models.ErrInvalid, "duplicate material %s", name)
+		}
+
+		names[name] = true
+
+		if flat {
+			continue
+		}
+
+		if material.Function == models.FunctionNone {
+			return nil, false, errors.Wrapf(models.ErrInvalid, "missing function for material %s", name)
+		}
+
+		if material.Thickness < 0 {
+			return nil, false, errors.Wrapf(models.ErrInvalid, "negative thickness for material %s", name)
+		}
 	}
 
-	if _, err := s.client.Spreadsheets.BatchUpdate(
-		s.spreadsheetID,
-		&sheets.BatchUpdateSpreadsheetRequest{
-			Requests: requests,
-		}).
-		Context(ctx).
-		Do(); err != nil {
-		return err
+	return materials, flat, nil
+}
+
+// completeFlatMaterials takes the thickness, function and structural flag of
+// flat materials from the materials of the sheet with the same name.
+func (s *Spreadsheet) completeFlatMaterials(materials models.WallMaterials) error {
+	for _, material := range materials {
+		existing, err := s.findMaterial(*material.Material.Name)
+		if err != nil {
+			return errors.Wrapf(models.ErrInvalid, "material %s isn't in the sheet, upload it with its Thickness and Function", *material.Material.Name)
+		}
+
+		material.Thickness = existing.Thickness
+		material.Function = existing.Function
+		material.IsStructural = existing.IsStructural
 	}
 
 	return nil
 }
+
+// colorValue writes colors back normalized as hex.
+func colorValue(color *models.Color) *string {
+	if color == nil {
+		return nil
+	}
+
+	value := color.Hex()
+
+	return &value
+}
+
+// uploadMaterials writes the thicknesses in the unit of the sheet, read from
+// the header alone so a tab with broken rows can still be replaced. When the
+// tab can be read, the colors whose cell couldn't be parsed must be set. Flat
+// materials need it to be read for their thickness and function.
+func (s *Spreadsheet) uploadMaterials(ctx context.Context, materials models.WallMaterials, flat bool) error {
+	s.ResetData()
+
+	if err := s.getMaterials(ctx); err != nil {
+		if flat {
+			return errors.Wrap(err, "Unable to read the thickness and function of flat materials")
+		}
+
+		log.Printf("Replacing materials that can't be read: %v", err)
+	} else {
+		if flat {
+			if err := s.completeFlatMaterials(materials); err != nil {
+				return err
+			}
+		}
+
+		if err := s.checkInvalidColors(materials); err != nil {
+			return err
+		}
+	}
+
+	headerRange := "MATERIALS!B1"
+	result, err := s.client.Spreadsheets.
+		Get(s.spreadsheetID).
+		Context(ctx).
+		Ranges(headerRange).
+		Fields(effectiveValue).
+		IncludeGridData(true).
+		Do()
+	if err != nil {
+		return errors.Wrapf(err, "Unable to retrieve spreadsheet %s", headerRange)
+	}
+
+	unit := s.materialsThicknessUnit(result.Sheets[0].Data[0].RowData)
+
+	rows := make([]*sheets.RowData, 0, len(materials))
+	for _, material := range materials {
+		rows = append(rows, materialRow(material, unit))
+	}
+
+	return s.uploadRows(ctx, materialsSheetID, rows)
+}
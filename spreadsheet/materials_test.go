@@ -0,0 +1,89 @@
+package spreadsheet
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+func TestCheckInvalidColors(t *testing.T) {
+	s := &Spreadsheet{
+		invalidColors: models.InvalidColors{
+			{Material: "Plasterboard", Field: "CutForegroundPatternColor", Value: "pinkish", Row: 3},
+		},
+	}
+
+	materials := testMaterials()
+	if err := s.checkInvalidColors(materials); !errors.Is(err, models.ErrInvalid) {
+		t.Errorf("got %v, want an ErrInvalid for a color that would be cleared", err)
+	}
+
+	materials[1].Material.CutForegroundPatternColor = &models.Color{R: 255, G: 192, B: 203}
+	if err := s.checkInvalidColors(materials); err != nil {
+		t.Errorf("got %v for a color set by the client", err)
+	}
+}
+
+func TestDecodeFlatMaterials(t *testing.T) {
+	s := &Spreadsheet{materials: testMaterials()}
+
+	bodies := map[models.Format]string{
+		models.FormatJSON: `[{"Name": "Plasterboard", "MaterialCategory": "Gypsum"}]`,
+		models.FormatCSV:  "Name,MaterialCategory\nPlasterboard,Gypsum\n",
+	}
+
+	for format, body := range bodies {
+		t.Run(string(format), func(t *testing.T) {
+			materials, flat, err := decodeMaterials(strings.NewReader(body), format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !flat {
+				t.Fatal("flat materials not recognized")
+			}
+
+			if err := s.completeFlatMaterials(materials); err != nil {
+				t.Fatal(err)
+			}
+
+			material := materials[0]
+			if material.Thickness != 12.5 || material.Function != models.FunctionFinish1 || *material.Material.MaterialCategory != "Gypsum" {
+				t.Errorf("unexpected material %+v %+v", material, material.Material)
+			}
+		})
+	}
+
+	materials, _, err := decodeMaterials(strings.NewReader(`[{"Name": "Timber"}]`), models.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.completeFlatMaterials(materials); !errors.Is(err, models.ErrInvalid) {
+		t.Errorf("got %v, want an ErrInvalid for a flat material missing from the sheet", err)
+	}
+}
+
+func TestMaterialsThicknessUnit(t *testing.T) {
+	s := &Spreadsheet{thicknessUnit: models.UnitMillimeter}
+
+	tests := map[string]models.LengthUnit{
+		"Thickness":             models.UnitMillimeter,
+		"Thickness (cm)":        models.UnitCentimeter,
+		"Thickness [in]":        models.UnitInch,
+		"Thickness (nominal)":   models.UnitMillimeter,
+		"Thickness (see notes)": models.UnitMillimeter,
+	}
+
+	for header, want := range tests {
+		row := &sheets.RowData{Values: []*sheets.CellData{{EffectiveValue: &sheets.ExtendedValue{StringValue: stringPtr(header)}}}}
+
+		if got := s.materialsThicknessUnit([]*sheets.RowData{row}); got != want {
+			t.Errorf("got %s for %q, want %s", got, header, want)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package spreadsheet
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+const (
+	patternsRange = "PATTERNS!A:E"
+
+	patternUnitsMillimeters = "MM"
+	patternUnitsInches      = "INCH"
+	patternTypeDrafting     = "DRAFTING"
+	patternTypeModel        = "MODEL"
+)
+
+// builtinPatterns exist in every Revit project and never come from a file.
+var builtinPatterns = map[string]bool{
+	"Solid fill":   true,
+	"<Solid fill>": true,
+}
+
+func (s *Spreadsheet) getPatterns(ctx context.Context) error {
+	ranges := "PATTERNS!A2:E"
+	result, err := s.client.Spreadsheets.
+		Get(s.spreadsheetID).
+		Context(ctx).
+		Ranges(ranges).
+		Fields(effectiveValue).
+		IncludeGridData(true).
+		Do()
+	if err != nil {
+		return errors.Wrapf(err, "Unable to retrieve spreadsheet %s", ranges)
+	}
+
+	patterns := make(models.FillPatterns, 0, len(result.Sheets[0].Data[0].RowData))
+	rowsFromSpreadsheet := result.Sheets[0].Data[0].RowData
+
+	for index, row := range rowsFromSpreadsheet {
+		name, err := readStringByCellIndex(row, 0)
+		if err != nil {
+			log.Printf("Skipping row %v: %v", index, err)
+
+			break
+		}
+
+		if name == "" {
+			break
+		}
+
+		pattern := &models.FillPattern{
+			Name:  name,
+			Type:  patternTypeDrafting,
+			Units: patternUnitsMillimeters,
+		}
+
+		if value := readPtrStringByCellIndex(row, 1); value != nil {
+			pattern.Description = *value
+		}
+
+		if value := readPtrStringByCellIndex(row, 2); value != nil && *value != "" {
+			pattern.Type = strings.ToUpper(*value)
+		}
+
+		if value := readPtrStringByCellIndex(row, 3); value != nil && *value != "" {
+			pattern.Units = strings.ToUpper(*value)
+		}
+
+		if value := readPtrStringByCellIndex(row, 4); value != nil {
+			for _, line := range strings.Split(*value, "\n") {
+				if line = strings.TrimSpace(line); line != "" {
+					pattern.Lines = append(pattern.Lines, line)
+				}
+			}
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	s.patterns = patterns
+
+	return nil
+}
+
+// readPatFile parses a Revit .pat file. The units line applies to the whole
+// file, ;%TYPE= lines to the pattern they follow, and any other line starting
+// with a semicolon is a comment.
+func readPatFile(src io.Reader) (models.FillPatterns, error) {
+	patterns := models.FillPatterns{}
+	units := patternUnitsMillimeters
+
+	var pattern *models.FillPattern
+
+	scanner := bufio.NewScanner(src)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), "\uFEFF"))
+
+		switch {
+		case text == "":
+			continue
+		case strings.HasPrefix(strings.ToUpper(text), ";%UNITS="):
+			units = strings.ToUpper(strings.TrimSpace(text[len(";%UNITS="):]))
+			if units != patternUnitsMillimeters && units != patternUnitsInches {
+				return nil, errors.Wrapf(models.ErrInvalid, "line %v: unknown units %s", line, units)
+			}
+
+			for _, pattern := range patterns {
+				pattern.Units = units
+			}
+		case strings.HasPrefix(strings.ToUpper(text), ";%TYPE="):
+			if pattern == nil {
+				return nil, errors.Wrapf(models.ErrInvalid, "line %v: type outside of a pattern", line)
+			}
+
+			pattern.Type = strings.ToUpper(strings.TrimSpace(text[len(";%TYPE="):]))
+			if pattern.Type != patternTypeDrafting && pattern.Type != patternTypeModel {
+				return nil, errors.Wrapf(models.ErrInvalid, "line %v: unknown pattern type %s", line, pattern.Type)
+			}
+		case strings.HasPrefix(text, ";"):
+			continue
+		case strings.HasPrefix(text, "*"):
+			name, description, _ := strings.Cut(text[1:], ",")
+
+			pattern = &models.FillPattern{
+				Name:        strings.TrimSpace(name),
+				Description: strings.TrimSpace(description),
+				Type:        patternTypeDrafting,
+				Units:       units,
+			}
+
+			if pattern.Name == "" {
+				return nil, errors.Wrapf(models.ErrInvalid, "line %v: pattern without name", line)
+			}
+
+			patterns = append(patterns, pattern)
+		default:
+			if pattern == nil {
+				return nil, errors.Wrapf(models.ErrInvalid, "line %v: definition outside of a pattern", line)
+			}
+
+			if _, err := parsePatternLine(text); err != nil {
+				return nil, errors.Wrapf(err, "line %v", line)
+			}
+
+			pattern.Lines = append(pattern.Lines, text)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "Unable to read pattern file")
+	}
+
+	return patterns, nil
+}
+
+// parsePatternLine reads "angle, x-origin, y-origin, shift, offset[, dash...]".
+func parsePatternLine(line string) ([]float64, error) {
+	fields := strings.Split(line, ",")
+	if len(fields) < 5 {
+		return nil, errors.Wrapf(models.ErrInvalid, "pattern line %q needs at least angle, origin, shift and offset", line)
+	}
+
+	values := make([]float64, 0, len(fields))
+	for _, field := range fields {
+		value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, errors.Wrapf(models.ErrInvalid, "pattern line %q: %q is not a number", line, field)
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// convertPatternLine rescales every length of a line, all the values but the
+// angle.
+func convertPatternLine(line string, factor float64) (string, error) {
+	values, err := parsePatternLine(line)
+	if err != nil {
+		return "", err
+	}
+
+	fields := make([]string, 0, len(values))
+	for index, value := range values {
+		if index > 0 {
+			value *= factor
+		}
+
+		fields = append(fields, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+
+	return strings.Join(fields, ","), nil
+}
+
+// writePatFile writes the patterns in millimeters, converting those defined
+// in inches since a .pat file only has one units line.
+func writePatFile(dst io.Writer, patterns models.FillPatterns) error {
+	var builder strings.Builder
+
+	builder.WriteString(";%UNITS=" + patternUnitsMillimeters + "\r\n")
+
+	for _, pattern := range patterns {
+		fmt.Fprintf(&builder, "*%s,%s\r\n", pattern.Name, pattern.Description)
+		fmt.Fprintf(&builder, ";%%TYPE=%s\r\n", pattern.Type)
+
+		for _, line := range pattern.Lines {
+			if pattern.Units == patternUnitsInches {
+				converted, err := convertPatternLine(line, 25.4)
+				if err != nil {
+					return errors.Wrapf(err, "pattern %s", pattern.Name)
+				}
+
+				line = converted
+			}
+
+			builder.WriteString(line + "\r\n")
+		}
+	}
+
+	_, err := io.WriteString(dst, builder.String())
+
+	return err
+}
+
+type materialPatternReference struct {
+	material, field, pattern string
+}
+
+func materialPatternReferences(materials models.WallMaterials) []materialPatternReference {
+	references := []materialPatternReference{}
+
+	for _, material := range materials {
+		if material.Material == nil || material.Material.Name == nil {
+			continue
+		}
+
+		for _, field := range []struct {
+			name  string
+			value *string
+		}{
+			{name: "CutBackgroundPatternId", value: material.Material.CutBackgroundPatternId},
+			{name: "CutForegroundPatternId", value: material.Material.CutForegroundPatternId},
+			{name: "SurfaceForegroundPatternId", value: material.Material.SurfaceForegroundPatternId},
+		} {
+			if field.value == nil || *field.value == "" || builtinPatterns[*field.value] {
+				continue
+			}
+
+			references = append(references, materialPatternReference{
+				material: *material.Material.Name,
+				field:    field.name,
+				pattern:  *field.value,
+			})
+		}
+	}
+
+	return references
+}
+
+func (s *Spreadsheet) loadPatterns(ctx context.Context) error {
+	if s.materials == nil {
+		if err := s.getMaterials(ctx); err != nil {
+			return errors.Wrap(err, "Unable to get materials")
+		}
+	}
+
+	if s.patterns == nil {
+		if err := s.getPatterns(ctx); err != nil {
+			return errors.Wrap(err, "Unable to get patterns")
+		}
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) findPattern(name string) *models.FillPattern {
+	for _, pattern := range s.patterns {
+		if pattern.Name == name {
+			return pattern
+		}
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadPatternsTo(ctx context.Context, dst io.Writer) error {
+	if s.patterns == nil {
+		if err := s.getPatterns(ctx); err != nil {
+			return errors.Wrap(err, "Unable to read patterns from spreadsheet")
+		}
+	}
+
+	if err := json.NewEncoder(dst).Encode(s.patterns); err != nil {
+		return errors.Wrap(err, "Unable to encode patterns to JSON")
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadMissingPatternsTo(ctx context.Context, dst io.Writer) error {
+	if err := s.loadPatterns(ctx); err != nil {
+		return err
+	}
+
+	missing := models.MissingPatterns{}
+	for _, reference := range materialPatternReferences(s.materials) {
+		if s.findPattern(reference.pattern) == nil {
+			missing = append(missing, &models.MissingPattern{
+				Material: reference.material,
+				Field:    reference.field,
+				Pattern:  reference.pattern,
+			})
+		}
+	}
+
+	if err := json.NewEncoder(dst).Encode(missing); err != nil {
+		return errors.Wrap(err, "Unable to encode missing patterns to JSON")
+	}
+
+	return nil
+}
+
+// ReadPatFileTo exports the patterns used by the materials, the ones missing
+// from the library are left out and reported by ReadMissingPatternsTo.
+func (s *Spreadsheet) ReadPatFileTo(ctx context.Context, dst io.Writer) error {
+	if err := s.loadPatterns(ctx); err != nil {
+		return err
+	}
+
+	patterns := models.FillPatterns{}
+	exported := map[string]bool{}
+
+	for _, reference := range materialPatternReferences(s.materials) {
+		pattern := s.findPattern(reference.pattern)
+		if pattern == nil || exported[pattern.Name] {
+			continue
+		}
+
+		exported[pattern.Name] = true
+		patterns = append(patterns, pattern)
+	}
+
+	if err := writePatFile(dst, patterns); err != nil {
+		return errors.Wrap(err, "Unable to write pattern file")
+	}
+
+	return nil
+}
+
+// UploadPatFileFrom parses a .pat file and replaces the content of the
+// PATTERNS tab with its patterns.
+func (s *Spreadsheet) UploadPatFileFrom(ctx context.Context, src io.Reader) error {
+	patterns, err := readPatFile(src)
+	if err != nil {
+		return errors.Wrap(err, "Unable to read pattern file")
+	}
+
+	if len(patterns) == 0 {
+		return errors.Wrap(models.ErrInvalid, "empty pattern file")
+	}
+
+	values := [][]interface{}{
+		{"Name", "Description", "Type", "Units", "Definition"},
+	}
+
+	for _, pattern := range patterns {
+		values = append(values, []interface{}{
+			pattern.Name, pattern.Description, pattern.Type, pattern.Units, strings.Join(pattern.Lines, "\n"),
+		})
+	}
+
+	if _, err := s.client.Spreadsheets.Values.Clear(
+		s.spreadsheetID,
+		patternsRange,
+		&sheets.ClearValuesRequest{}).
+		Context(ctx).
+		Do(); err != nil {
+		return errors.Wrapf(err, "Unable to clear spreadsheet %s", patternsRange)
+	}
+
+	if _, err := s.client.Spreadsheets.Values.Update(
+		s.spreadsheetID,
+		patternsRange,
+		&sheets.ValueRange{Values: values}).
+		ValueInputOption("RAW").
+		Context(ctx).
+		Do(); err != nil {
+		return errors.Wrapf(err, "Unable to update spreadsheet %s", patternsRange)
+	}
+
+	s.patterns = patterns
+
+	return nil
+}
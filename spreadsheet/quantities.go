@@ -0,0 +1,117 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// computeQuantities aggregates per material the net wall area (m²) and the
+// volume (m³) of the uploaded wall instances, lengths and heights are in
+// meters and layer thicknesses in millimeters.
+func (s *Spreadsheet) computeQuantities(ctx context.Context, instances models.WallInstances) (models.MaterialQuantities, error) {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	quantities := models.MaterialQuantities{}
+	quantitiesByMaterial := map[string]*models.MaterialQuantity{}
+
+	for index, instance := range instances {
+		if instance == nil {
+			return nil, errors.Wrapf(models.ErrInvalid, "null wall instance %v", index)
+		}
+
+		if instance.Length < 0 || instance.Height < 0 || instance.OpeningsArea < 0 {
+			return nil, errors.Wrapf(models.ErrInvalid, "negative length, height or openings area in wall instance %v", index)
+		}
+
+		assembly := findWallAssembly(assemblies, instance.AreaInternal, instance.AreaExternal)
+		if assembly == nil {
+			return nil, errors.Wrapf(models.ErrInvalid, "unknown relation %s → %s in wall instance %v", instance.AreaInternal, instance.AreaExternal, index)
+		}
+
+		area := instance.Length*instance.Height - instance.OpeningsArea
+		if area < 0 {
+			return nil, errors.Wrapf(models.ErrInvalid, "openings area larger than the wall of wall instance %v", index)
+		}
+
+		for _, layer := range assembly.Layers {
+			name := layerMaterialName(layer)
+
+			quantity, ok := quantitiesByMaterial[name]
+			if !ok {
+				quantity = &models.MaterialQuantity{
+					Material: name,
+				}
+				quantitiesByMaterial[name] = quantity
+				quantities = append(quantities, quantity)
+			}
+
+			quantity.Area += area
+			quantity.Volume += area * layer.Thickness / 1000
+		}
+	}
+
+	return quantities, nil
+}
+
+// findWallAssembly looks up the assembly of a relation by area names, an
+// empty external name matches relations without external area and a relation
+// flagged as SameArea also matches its own area as external.
+func findWallAssembly(assemblies models.WallAssemblies, internal, external string) *models.WallAssembly {
+	for _, assembly := range assemblies {
+		relation := assembly.Relation
+		if relation.AreaInternal == nil || relation.AreaInternal.Name != internal {
+			continue
+		}
+
+		switch {
+		case relation.AreaExternal != nil && relation.AreaExternal.Name == external:
+			return assembly
+		case relation.AreaExternal == nil && external == "":
+			return assembly
+		case relation.SameArea && external == internal:
+			return assembly
+		}
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadQuantitiesTo(ctx context.Context, src io.Reader, dst io.Writer, format models.Format) error {
+	var instances models.WallInstances
+
+	if err := json.NewDecoder(src).Decode(&instances); err != nil {
+		return errors.Wrap(models.ErrInvalid, "Unable to decode wall instances from JSON: "+err.Error())
+	}
+
+	quantities, err := s.computeQuantities(ctx, instances)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute quantities")
+	}
+
+	if format == models.FormatYAML {
+		return errors.Wrap(writeData(dst, format, "QUANTITIES", quantities), "Unable to encode quantities to YAML")
+	}
+
+	if format != models.FormatJSON {
+		rows := [][]interface{}{{"Material", "Area", "Volume"}}
+		for _, quantity := range quantities {
+			rows = append(rows, []interface{}{quantity.Material, quantity.Area, quantity.Volume})
+		}
+
+		return errors.Wrapf(writeTable(dst, format, "QUANTITIES", rows), "Unable to encode quantities to %s", format)
+	}
+
+	if err := json.NewEncoder(dst).Encode(quantities); err != nil {
+		return errors.Wrap(err, "Unable to encode quantities to JSON")
+	}
+
+	return nil
+}
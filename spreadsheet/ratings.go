@@ -0,0 +1,180 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+func (s *Spreadsheet) getAreasRequirements(ctx context.Context) error {
+	ranges := "AREA_REQUIREMENTS!A2:D"
+	result, err := s.client.Spreadsheets.
+		Get(s.spreadsheetID).
+		Context(ctx).
+		Ranges(ranges).
+		Fields(effectiveValue).
+		IncludeGridData(true).
+		Do()
+	if err != nil {
+		return errors.Wrapf(err, "Unable to retrieve spreadsheet %s", ranges)
+	}
+
+	requirements := make(models.AreasRequirements, 0, len(result.Sheets[0].Data[0].RowData))
+	rowsFromSpreadsheet := result.Sheets[0].Data[0].RowData
+
+	for index, row := range rowsFromSpreadsheet {
+		areaA, err := readStringByCellIndex(row, 0)
+		if err != nil {
+			log.Printf("Skipping row %v: %v", index, err)
+
+			break
+		}
+
+		if areaA == "" {
+			break
+		}
+
+		areaB := ""
+		if value := readPtrStringByCellIndex(row, 1); value != nil {
+			areaB = *value
+		}
+
+		requirements = append(requirements, &models.AreaRequirement{
+			AreaA:          areaA,
+			AreaB:          areaB,
+			FireRating:     readPtrNumberByCellIndex(row, 2),
+			AcousticRating: readPtrNumberByCellIndex(row, 3),
+		})
+	}
+
+	s.requirements = requirements
+
+	return nil
+}
+
+// matchesRelation tells whether the requirement applies to the relation in
+// either direction. Requirements name either areas or use classes, an empty
+// AreaB stands for relations without external area.
+func matchesRelation(requirement *models.AreaRequirement, relation *models.AreaRelation) bool {
+	internal := relation.AreaInternal
+	external := relation.AreaExternal
+
+	if relation.SameArea {
+		external = internal
+	}
+
+	return (matchesRequirementArea(requirement.AreaA, internal) && matchesRequirementArea(requirement.AreaB, external)) ||
+		(matchesRequirementArea(requirement.AreaA, external) && matchesRequirementArea(requirement.AreaB, internal))
+}
+
+func matchesRequirementArea(key string, area *models.Area) bool {
+	if area == nil {
+		return key == ""
+	}
+
+	return key == area.Name || (area.UseClass != nil && key == *area.UseClass)
+}
+
+// checkWallRatings compares what an assembly provides with the strictest
+// requirement of its area pair. Ratings aren't additive, so when the relation
+// doesn't state them the assembly is rated by its best layer.
+func checkWallRatings(assembly *models.WallAssembly, requirements models.AreasRequirements) *models.WallRatingCheck {
+	check := &models.WallRatingCheck{
+		WallType:       assembly.WallType,
+		Relation:       assembly.Relation,
+		FireRating:     assembly.Relation.FireRating,
+		AcousticRating: assembly.Relation.AcousticRating,
+		Violations:     []string{},
+	}
+
+	for _, requirement := range requirements {
+		if !matchesRelation(requirement, assembly.Relation) {
+			continue
+		}
+
+		check.RequiredFireRating = maxRating(check.RequiredFireRating, requirement.FireRating)
+		check.RequiredAcousticRating = maxRating(check.RequiredAcousticRating, requirement.AcousticRating)
+	}
+
+	for _, layer := range assembly.Layers {
+		if layer.Material == nil {
+			continue
+		}
+
+		if assembly.Relation.FireRating == nil {
+			check.FireRating = maxRating(check.FireRating, layer.Material.FireRating)
+		}
+
+		if assembly.Relation.AcousticRating == nil {
+			check.AcousticRating = maxRating(check.AcousticRating, layer.Material.AcousticRating)
+		}
+	}
+
+	if violation := ratingViolation("fire rating", "min", check.RequiredFireRating, check.FireRating); violation != "" {
+		check.Violations = append(check.Violations, violation)
+	}
+
+	if violation := ratingViolation("acoustic rating", "dB", check.RequiredAcousticRating, check.AcousticRating); violation != "" {
+		check.Violations = append(check.Violations, violation)
+	}
+
+	return check
+}
+
+func maxRating(current, candidate *float64) *float64 {
+	if candidate == nil {
+		return current
+	}
+
+	if current == nil || *candidate > *current {
+		return candidate
+	}
+
+	return current
+}
+
+func ratingViolation(name, unit string, required, provided *float64) string {
+	if required == nil {
+		return ""
+	}
+
+	if provided == nil {
+		return fmt.Sprintf("%s of %g %s required but the wall has none", name, *required, unit)
+	}
+
+	if *provided < *required {
+		return fmt.Sprintf("%s of %g %s required but the wall provides %g %s", name, *required, unit, *provided, unit)
+	}
+
+	return ""
+}
+
+func (s *Spreadsheet) ReadWallTypesRatingsTo(ctx context.Context, dst io.Writer) error {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	if s.requirements == nil {
+		if err := s.getAreasRequirements(ctx); err != nil {
+			return errors.Wrap(err, "Unable to read area requirements from spreadsheet")
+		}
+	}
+
+	checks := make(models.WallRatingChecks, 0, len(assemblies))
+	for _, assembly := range assemblies {
+		checks = append(checks, checkWallRatings(assembly, s.requirements))
+	}
+
+	if err := json.NewEncoder(dst).Encode(checks); err != nil {
+		return errors.Wrap(err, "Unable to encode wall ratings to JSON")
+	}
+
+	return nil
+}
@@ -0,0 +1,712 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+// noExternalArea stands for the missing external area of a relation in the
+// URL of its resource.
+const noExternalArea = "-"
+
+func relationKey(areaInternal, areaExternal *models.Area) string {
+	key := ""
+	if areaInternal != nil {
+		key = areaInternal.Name
+	}
+
+	key += "\x00"
+	if areaExternal != nil {
+		key += areaExternal.Name
+	}
+
+	return key
+}
+
+func stringCell(value *string) *sheets.CellData {
+	return &sheets.CellData{UserEnteredValue: &sheets.ExtendedValue{StringValue: value}}
+}
+
+func numberCell(value *float64) *sheets.CellData {
+	return &sheets.CellData{UserEnteredValue: &sheets.ExtendedValue{NumberValue: value}}
+}
+
+func boolCell(value bool) *sheets.CellData {
+	return &sheets.CellData{UserEnteredValue: &sheets.ExtendedValue{BoolValue: &value}}
+}
+
+// materialRow follows the layout read by getMaterials, the thickness being
+// written back in the unit of the sheet.
+func materialRow(material *models.WallMaterial, unit models.LengthUnit) *sheets.RowData {
+	thickness := unit.FromMillimeters(material.Thickness)
+	function := material.Function.String()
+
+	return &sheets.RowData{
+		Values: []*sheets.CellData{
+			boolCell(material.IsStructural),
+			numberCell(&thickness),
+			stringCell(&function),
+			stringCell(material.Material.Name),
+			stringCell(material.Material.MaterialCategory),
+			stringCell(colorValue(material.Material.CutBackgroundPatternColor)),
+			stringCell(material.Material.CutBackgroundPatternId),
+			stringCell(colorValue(material.Material.CutForegroundPatternColor)),
+			stringCell(material.Material.CutForegroundPatternId),
+			stringCell(colorValue(material.Material.SurfaceForegroundPatternColor)),
+			stringCell(material.Material.SurfaceForegroundPatternId),
+			stringCell(material.Material.Mark),
+			stringCell(material.Material.Keynote),
+			stringCell(material.Material.Description),
+			stringCell(material.Material.Manufacturer),
+			numberCell(material.Material.Conductivity),
+			numberCell(material.Material.Density),
+			numberCell(material.Material.SpecificHeat),
+			numberCell(material.Material.CarbonFactor),
+			numberCell(material.Material.UnitPrice),
+			stringCell(material.Material.Unit),
+			stringCell(material.Material.Currency),
+			numberCell(material.Material.FireRating),
+			numberCell(material.Material.AcousticRating),
+		},
+	}
+}
+
+func areaRow(area *models.Area) *sheets.RowData {
+	areaType := string(area.Type)
+	properties := formatAreaProperties(area.Properties)
+
+	return &sheets.RowData{
+		Values: []*sheets.CellData{
+			stringCell(&area.Name),
+			stringCell(&areaType),
+			stringCell(area.Level),
+			stringCell(area.Building),
+			stringCell(area.UseClass),
+			stringCell(&properties),
+		},
+	}
+}
+
+func relationRow(relation *models.AreaRelation) *sheets.RowData {
+	var areaExternal, central *string
+
+	if relation.AreaExternal != nil {
+		areaExternal = &relation.AreaExternal.Name
+	}
+
+	if relation.Central != nil && relation.Central.Material != nil {
+		central = relation.Central.Material.Name
+	}
+
+	return &sheets.RowData{
+		Values: []*sheets.CellData{
+			boolCell(relation.SameArea),
+			stringCell(&relation.AreaInternal.Name),
+			stringCell(areaExternal),
+			stringCell(central),
+			stringCell(relation.WallKeynote),
+			numberCell(relation.WallArea),
+			numberCell(relation.FireRating),
+			numberCell(relation.AcousticRating),
+		},
+	}
+}
+
+// uploadRows replaces every record of a tab with rows, the rows left over
+// from a longer upload are cleared so they are not read back.
+func (s *Spreadsheet) uploadRows(ctx context.Context, sheetID int64, rows []*sheets.RowData) error {
+	columns := int64(0)
+	for _, row := range rows {
+		if int64(len(row.Values)) > columns {
+			columns = int64(len(row.Values))
+		}
+	}
+
+	if _, err := s.client.Spreadsheets.BatchUpdate(
+		s.spreadsheetID,
+		&sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				{
+					UpdateCells: &sheets.UpdateCellsRequest{
+						Fields: "*",
+						Range: &sheets.GridRange{
+							SheetId:          sheetID,
+							StartRowIndex:    1,
+							EndRowIndex:      int64(len(rows)) + 1,
+							StartColumnIndex: 0,
+							EndColumnIndex:   columns,
+						},
+						Rows: rows,
+					},
+				},
+				{
+					UpdateCells: &sheets.UpdateCellsRequest{
+						Fields: "*",
+						Range: &sheets.GridRange{
+							SheetId:          sheetID,
+							StartRowIndex:    int64(len(rows)) + 1,
+							StartColumnIndex: 0,
+							EndColumnIndex:   columns,
+						},
+					},
+				},
+			},
+		}).
+		Context(ctx).
+		Do(); err != nil {
+		return err
+	}
+
+	s.ResetData()
+
+	return nil
+}
+
+// writeRow overwrites the row of key, or writes the record right after the
+// last one when the key is new. rows must come from a fresh read of the tab,
+// the callers drop the caches first since rows may have been moved in the
+// sheet since they were read. Every cache is dropped since other records
+// hold pointers to the one being written.
+func (s *Spreadsheet) writeRow(ctx context.Context, sheetID int64, rows sheetRows, key string, row *sheets.RowData) (bool, error) {
+	index, ok := rows.indexes[key]
+	if !ok {
+		index = rows.next
+	}
+
+	if _, err := s.client.Spreadsheets.BatchUpdate(
+		s.spreadsheetID,
+		&sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				{
+					UpdateCells: &sheets.UpdateCellsRequest{
+						Fields: "*",
+						Range: &sheets.GridRange{
+							SheetId:          sheetID,
+							StartRowIndex:    index,
+							EndRowIndex:      index + 1,
+							StartColumnIndex: 0,
+							EndColumnIndex:   int64(len(row.Values)),
+						},
+						Rows: []*sheets.RowData{row},
+					},
+				},
+			},
+		}).
+		Context(ctx).
+		Do(); err != nil {
+		return false, err
+	}
+
+	s.ResetData()
+
+	return !ok, nil
+}
+
+func (s *Spreadsheet) deleteRow(ctx context.Context, sheetID int64, rows sheetRows, key string) error {
+	index, ok := rows.indexes[key]
+	if !ok {
+		return models.ErrNotFound
+	}
+
+	if _, err := s.client.Spreadsheets.BatchUpdate(
+		s.spreadsheetID,
+		&sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				{
+					DeleteDimension: &sheets.DeleteDimensionRequest{
+						Range: &sheets.DimensionRange{
+							SheetId:    sheetID,
+							Dimension:  "ROWS",
+							StartIndex: index,
+							EndIndex:   index + 1,
+						},
+					},
+				},
+			},
+		}).
+		Context(ctx).
+		Do(); err != nil {
+		return err
+	}
+
+	s.ResetData()
+
+	return nil
+}
+
+// patchRecord decodes src onto a deep copy of record, fields missing from
+// src keep their value and null clears them.
+func patchRecord(record interface{}, src io.Reader, patched interface{}) error {
+	encoded, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(encoded, patched); err != nil {
+		return err
+	}
+
+	return decodeRecord(src, patched)
+}
+
+func decodeRecord(src io.Reader, record interface{}) error {
+	if err := json.NewDecoder(src).Decode(record); err != nil {
+		if errors.Is(err, models.ErrInvalid) {
+			return err
+		}
+
+		return errors.Wrap(models.ErrInvalid, err.Error())
+	}
+
+	return nil
+}
+
+// invalidReference turns a record pointing to a missing one into a client
+// error, the resource in the URL being the only one that can be not found.
+func invalidReference(err error) error {
+	if errors.Is(err, models.ErrNotFound) {
+		return errors.Wrap(models.ErrInvalid, err.Error())
+	}
+
+	return err
+}
+
+func (s *Spreadsheet) loadMaterials(ctx context.Context) error {
+	if s.materials == nil {
+		if err := s.getMaterials(ctx); err != nil {
+			return errors.Wrap(err, "Unable to get materials")
+		}
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) loadAreas(ctx context.Context) error {
+	if s.areas == nil {
+		if err := s.getAreas(ctx); err != nil {
+			return errors.Wrap(err, "Unable to get areas")
+		}
+	}
+
+	return nil
+}
+
+// loadReferences reads the tabs pointing to areas and materials.
+func (s *Spreadsheet) loadReferences(ctx context.Context) error {
+	if s.areasMaterials == nil {
+		if err := s.getAreasMaterials(ctx); err != nil {
+			return errors.Wrap(err, "Unable to get areas materials")
+		}
+	}
+
+	if s.relations == nil {
+		if err := s.getAreasRelations(ctx); err != nil {
+			return errors.Wrap(err, "Unable to get areas relations")
+		}
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadMaterialTo(ctx context.Context, name string, dst io.Writer, options models.ReadOptions) error {
+	if err := s.loadMaterials(ctx); err != nil {
+		return err
+	}
+
+	material, err := s.findMaterial(name)
+	if err != nil {
+		return err
+	}
+
+	if err := writeData(dst, options.Format, "Material", convertWallMaterials(models.WallMaterials{material}, options.Units)[0]); err != nil {
+		return errors.Wrap(err, "Unable to encode material")
+	}
+
+	return nil
+}
+
+// WriteMaterialFrom creates or replaces the material called name, it tells
+// whether the material was created. The thickness is read and written back
+// in units.
+func (s *Spreadsheet) WriteMaterialFrom(ctx context.Context, name string, src io.Reader, dst io.Writer, units models.LengthUnit) (bool, error) {
+	s.ResetData()
+
+	material := &models.WallMaterial{}
+	if err := decodeRecord(src, material); err != nil {
+		return false, errors.Wrap(err, "Unable to decode material")
+	}
+
+	material.Thickness = units.ToMillimeters(material.Thickness)
+
+	return s.writeMaterial(ctx, name, material, dst, units)
+}
+
+func (s *Spreadsheet) PatchMaterialFrom(ctx context.Context, name string, src io.Reader, dst io.Writer, units models.LengthUnit) error {
+	s.ResetData()
+
+	if err := s.loadMaterials(ctx); err != nil {
+		return err
+	}
+
+	material, err := s.findMaterial(name)
+	if err != nil {
+		return err
+	}
+
+	patched := &models.WallMaterial{}
+	if err := patchRecord(convertWallMaterial(material, units), src, patched); err != nil {
+		return errors.Wrap(err, "Unable to decode material")
+	}
+
+	patched.Thickness = units.ToMillimeters(patched.Thickness)
+
+	_, err = s.writeMaterial(ctx, name, patched, dst, units)
+
+	return err
+}
+
+func (s *Spreadsheet) writeMaterial(ctx context.Context, name string, material *models.WallMaterial, dst io.Writer, units models.LengthUnit) (bool, error) {
+	if material.Material == nil {
+		material.Material = &models.Material{}
+	}
+
+	if material.Material.Name == nil || *material.Material.Name == "" {
+		material.Material.Name = &name
+	}
+
+	if *material.Material.Name != name {
+		return false, errors.Wrapf(models.ErrInvalid, "material name %s doesn't match %s", *material.Material.Name, name)
+	}
+
+	// getMaterials can't read a row without function.
+	if material.Function == models.FunctionNone {
+		return false, errors.Wrapf(models.ErrInvalid, "missing function for material %s", name)
+	}
+
+	if material.Thickness < 0 {
+		return false, errors.Wrapf(models.ErrInvalid, "negative thickness for material %s", name)
+	}
+
+	if err := s.loadMaterials(ctx); err != nil {
+		return false, err
+	}
+
+	if err := s.checkInvalidColors(models.WallMaterials{material}); err != nil {
+		return false, err
+	}
+
+	created, err := s.writeRow(ctx, materialsSheetID, s.materialsRows, name, materialRow(material, s.materialsUnit))
+	if err != nil {
+		return false, errors.Wrapf(err, "Unable to write material %s to spreadsheet", name)
+	}
+
+	if err := json.NewEncoder(dst).Encode(convertWallMaterial(material, units)); err != nil {
+		return false, errors.Wrap(err, "Unable to encode material to JSON")
+	}
+
+	return created, nil
+}
+
+// DeleteMaterial refuses to delete a material still used by an area or a
+// relation, reading them would fail afterwards.
+func (s *Spreadsheet) DeleteMaterial(ctx context.Context, name string) error {
+	s.ResetData()
+
+	if err := s.loadReferences(ctx); err != nil {
+		return err
+	}
+
+	material, err := s.findMaterial(name)
+	if err != nil {
+		return err
+	}
+
+	for _, areaMaterials := range s.areasMaterials {
+		for _, areaMaterial := range areaMaterials.Materials {
+			if areaMaterial == material {
+				return errors.Wrapf(models.ErrInvalid, "material %s is used by area %s", name, areaMaterials.Area.Name)
+			}
+		}
+	}
+
+	for _, relation := range s.relations {
+		if relation.Central == material {
+			return errors.Wrapf(models.ErrInvalid, "material %s is used by relation %s", name, relationLabel(relation))
+		}
+	}
+
+	if err := s.deleteRow(ctx, materialsSheetID, s.materialsRows, name); err != nil {
+		return errors.Wrapf(err, "Unable to delete material %s from spreadsheet", name)
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadAreaTo(ctx context.Context, name string, dst io.Writer, options models.ReadOptions) error {
+	if err := s.loadAreas(ctx); err != nil {
+		return err
+	}
+
+	area, err := s.findArea(name)
+	if err != nil {
+		return err
+	}
+
+	if err := writeData(dst, options.Format, "Area", area); err != nil {
+		return errors.Wrap(err, "Unable to encode area")
+	}
+
+	return nil
+}
+
+// WriteAreaFrom creates or replaces the area called name, it tells whether
+// the area was created.
+func (s *Spreadsheet) WriteAreaFrom(ctx context.Context, name string, src io.Reader, dst io.Writer) (bool, error) {
+	s.ResetData()
+
+	area := &models.Area{}
+	if err := decodeRecord(src, area); err != nil {
+		return false, errors.Wrap(err, "Unable to decode area")
+	}
+
+	return s.writeArea(ctx, name, area, dst)
+}
+
+func (s *Spreadsheet) PatchAreaFrom(ctx context.Context, name string, src io.Reader, dst io.Writer) error {
+	s.ResetData()
+
+	if err := s.loadAreas(ctx); err != nil {
+		return err
+	}
+
+	area, err := s.findArea(name)
+	if err != nil {
+		return err
+	}
+
+	patched := &models.Area{}
+	if err := patchRecord(area, src, patched); err != nil {
+		return errors.Wrap(err, "Unable to decode area")
+	}
+
+	_, err = s.writeArea(ctx, name, patched, dst)
+
+	return err
+}
+
+func (s *Spreadsheet) writeArea(ctx context.Context, name string, area *models.Area, dst io.Writer) (bool, error) {
+	if area.Name == "" {
+		area.Name = name
+	}
+
+	if area.Name != name {
+		return false, errors.Wrapf(models.ErrInvalid, "area name %s doesn't match %s", area.Name, name)
+	}
+
+	areaType, err := models.ParseAreaType(string(area.Type))
+	if err != nil {
+		return false, errors.Wrapf(err, "error reading type of area %s", name)
+	}
+
+	area.Type = areaType
+
+	if err := s.loadAreas(ctx); err != nil {
+		return false, err
+	}
+
+	created, err := s.writeRow(ctx, areasSheetID, s.areasRows, name, areaRow(area))
+	if err != nil {
+		return false, errors.Wrapf(err, "Unable to write area %s to spreadsheet", name)
+	}
+
+	if err := json.NewEncoder(dst).Encode(area); err != nil {
+		return false, errors.Wrap(err, "Unable to encode area to JSON")
+	}
+
+	return created, nil
+}
+
+// DeleteArea refuses to delete an area still having materials or relations.
+func (s *Spreadsheet) DeleteArea(ctx context.Context, name string) error {
+	s.ResetData()
+
+	if err := s.loadReferences(ctx); err != nil {
+		return err
+	}
+
+	if _, err := s.findArea(name); err != nil {
+		return err
+	}
+
+	for _, areaMaterials := range s.areasMaterials {
+		if areaMaterials.Area.Name == name {
+			return errors.Wrapf(models.ErrInvalid, "area %s has materials", name)
+		}
+	}
+
+	for _, relation := range s.relations {
+		if relation.AreaInternal.Name == name || (relation.AreaExternal != nil && relation.AreaExternal.Name == name) {
+			return errors.Wrapf(models.ErrInvalid, "area %s is used by relation %s", name, relationLabel(relation))
+		}
+	}
+
+	if err := s.deleteRow(ctx, areasSheetID, s.areasRows, name); err != nil {
+		return errors.Wrapf(err, "Unable to delete area %s from spreadsheet", name)
+	}
+
+	return nil
+}
+
+// findAreaRelation looks a relation up by the names in its URL, external
+// being noExternalArea for a relation without external area.
+func (s *Spreadsheet) findAreaRelation(ctx context.Context, internal, external string) (*models.AreaRelation, error) {
+	if s.relations == nil {
+		if err := s.getAreasRelations(ctx); err != nil {
+			return nil, errors.Wrap(err, "Unable to get areas relations")
+		}
+	}
+
+	for _, relation := range s.relations {
+		if relation.AreaInternal.Name != internal {
+			continue
+		}
+
+		if (relation.AreaExternal == nil && external == noExternalArea) ||
+			(relation.AreaExternal != nil && relation.AreaExternal.Name == external) {
+			return relation, nil
+		}
+	}
+
+	return nil, errors.Wrapf(models.ErrNotFound, "relation %s → %s", internal, external)
+}
+
+func (s *Spreadsheet) ReadAreaRelationTo(ctx context.Context, internal, external string, dst io.Writer, options models.ReadOptions) error {
+	relation, err := s.findAreaRelation(ctx, internal, external)
+	if err != nil {
+		return err
+	}
+
+	if err := writeData(dst, options.Format, "Area relation", convertAreasRelations(models.AreasRelations{relation}, options.Units)[0]); err != nil {
+		return errors.Wrap(err, "Unable to encode area relation")
+	}
+
+	return nil
+}
+
+// WriteAreaRelationFrom creates or replaces the relation between two areas,
+// it tells whether the relation was created. Areas and the central material
+// are given by name, the rest of their fields is ignored. The relation is
+// written back with the central thickness in units.
+func (s *Spreadsheet) WriteAreaRelationFrom(ctx context.Context, internal, external string, src io.Reader, dst io.Writer, units models.LengthUnit) (bool, error) {
+	s.ResetData()
+
+	relation := &models.AreaRelation{}
+	if err := decodeRecord(src, relation); err != nil {
+		return false, errors.Wrap(err, "Unable to decode area relation")
+	}
+
+	return s.writeAreaRelation(ctx, internal, external, relation, dst, units)
+}
+
+func (s *Spreadsheet) PatchAreaRelationFrom(ctx context.Context, internal, external string, src io.Reader, dst io.Writer, units models.LengthUnit) error {
+	s.ResetData()
+
+	relation, err := s.findAreaRelation(ctx, internal, external)
+	if err != nil {
+		return err
+	}
+
+	patched := &models.AreaRelation{}
+	if err := patchRecord(convertAreasRelations(models.AreasRelations{relation}, units)[0], src, patched); err != nil {
+		return errors.Wrap(err, "Unable to decode area relation")
+	}
+
+	_, err = s.writeAreaRelation(ctx, internal, external, patched, dst, units)
+
+	return err
+}
+
+func (s *Spreadsheet) writeAreaRelation(ctx context.Context, internal, external string, relation *models.AreaRelation, dst io.Writer, units models.LengthUnit) (bool, error) {
+	if s.relations == nil {
+		if err := s.getAreasRelations(ctx); err != nil {
+			return false, errors.Wrap(err, "Unable to get areas relations")
+		}
+	}
+
+	if relation.AreaInternal != nil && relation.AreaInternal.Name != "" && relation.AreaInternal.Name != internal {
+		return false, errors.Wrapf(models.ErrInvalid, "internal area %s doesn't match %s", relation.AreaInternal.Name, internal)
+	}
+
+	areaInternal, err := s.findArea(internal)
+	if err != nil {
+		return false, invalidReference(err)
+	}
+
+	relation.AreaInternal = areaInternal
+
+	if external == noExternalArea {
+		if relation.AreaExternal != nil && relation.AreaExternal.Name != "" {
+			return false, errors.Wrapf(models.ErrInvalid, "external area %s doesn't match %s", relation.AreaExternal.Name, external)
+		}
+
+		relation.AreaExternal = nil
+	} else {
+		if relation.AreaExternal != nil && relation.AreaExternal.Name != "" && relation.AreaExternal.Name != external {
+			return false, errors.Wrapf(models.ErrInvalid, "external area %s doesn't match %s", relation.AreaExternal.Name, external)
+		}
+
+		areaExternal, err := s.findArea(external)
+		if err != nil {
+			return false, invalidReference(err)
+		}
+
+		relation.AreaExternal = areaExternal
+	}
+
+	if relation.Central != nil && relation.Central.Material != nil && relation.Central.Material.Name != nil {
+		central, err := s.findMaterial(*relation.Central.Material.Name)
+		if err != nil {
+			return false, invalidReference(err)
+		}
+
+		relation.Central = central
+	} else {
+		relation.Central = nil
+	}
+
+	key := relationKey(relation.AreaInternal, relation.AreaExternal)
+
+	created, err := s.writeRow(ctx, areasRelationsSheetID, s.relationsRows, key, relationRow(relation))
+	if err != nil {
+		return false, errors.Wrapf(err, "Unable to write relation %s to spreadsheet", relationLabel(relation))
+	}
+
+	if err := json.NewEncoder(dst).Encode(convertAreasRelations(models.AreasRelations{relation}, units)[0]); err != nil {
+		return false, errors.Wrap(err, "Unable to encode area relation to JSON")
+	}
+
+	return created, nil
+}
+
+func (s *Spreadsheet) DeleteAreaRelation(ctx context.Context, internal, external string) error {
+	s.ResetData()
+
+	relation, err := s.findAreaRelation(ctx, internal, external)
+	if err != nil {
+		return err
+	}
+
+	key := relationKey(relation.AreaInternal, relation.AreaExternal)
+
+	if err := s.deleteRow(ctx, areasRelationsSheetID, s.relationsRows, key); err != nil {
+		return errors.Wrapf(err, "Unable to delete relation %s from spreadsheet", relationLabel(relation))
+	}
+
+	return nil
+}
@@ -0,0 +1,219 @@
+package spreadsheet
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// Section drawings are laid out in pixels, layers go from left (exterior)
+// to right (interior) at sectionScale pixels per millimeter.
+const (
+	sectionScale        = 1.5
+	sectionMargin       = 20.0
+	sectionTitleHeight  = 30.0
+	sectionHeight       = 160.0
+	sectionLabelsHeight = 220.0
+	sectionMinWidth     = 320.0
+
+	sectionDefaultBackground = "#ffffff"
+	sectionDefaultForeground = "#000000"
+)
+
+func sectionColor(color *models.Color, fallback string) string {
+	if color == nil {
+		return fallback
+	}
+
+	return color.Hex()
+}
+
+func sectionWidth(wallType *models.WallType) float64 {
+	width := wallType.TotalThickness*sectionScale + 2*sectionMargin
+
+	if width < sectionMinWidth {
+		return sectionMinWidth
+	}
+
+	return width
+}
+
+func sectionLayerLabel(layer *models.WallLayer) string {
+	parts := []string{}
+
+	if layer.Material != nil {
+		if layer.Material.Mark != nil && *layer.Material.Mark != "" {
+			parts = append(parts, *layer.Material.Mark)
+		}
+
+		if layer.Material.Keynote != nil && *layer.Material.Keynote != "" {
+			parts = append(parts, *layer.Material.Keynote)
+		}
+	}
+
+	parts = append(parts, layerMaterialName(layer), strconv.FormatFloat(layer.Thickness, 'f', -1, 64)+" mm")
+
+	return strings.Join(parts, " · ")
+}
+
+// writeWallTypeSection draws a wall type at (x, y), id keeps the hatch
+// patterns of several sections in the same document apart.
+func writeWallTypeSection(builder *strings.Builder, wallType *models.WallType, id string, x, y float64) {
+	fmt.Fprintf(builder, `<g id="%s" transform="translate(%g,%g)">`+"\n", id, x, y)
+	fmt.Fprintf(builder, `<text x="%g" y="%g" font-family="sans-serif" font-size="14" font-weight="bold">%s</text>`+"\n",
+		sectionMargin, sectionTitleHeight-10, html.EscapeString(fmt.Sprintf("%s (%g mm)", wallType.Name, wallType.TotalThickness)))
+
+	offset := sectionMargin
+	top := sectionTitleHeight
+
+	for index, layer := range wallType.Layers {
+		var background, foreground *models.Color
+		if layer.Material != nil {
+			background = layer.Material.CutBackgroundPatternColor
+			foreground = layer.Material.CutForegroundPatternColor
+		}
+
+		fill := sectionColor(background, sectionDefaultBackground)
+		width := layer.Thickness * sectionScale
+		center := offset + width/2
+
+		if width == 0 {
+			fmt.Fprintf(builder, `<line x1="%g" y1="%g" x2="%g" y2="%g" stroke="%s" stroke-width="2" stroke-dasharray="6,3"/>`+"\n",
+				offset, top, offset, top+sectionHeight, sectionColor(foreground, sectionDefaultForeground))
+		} else {
+			if foreground != nil {
+				patternID := fmt.Sprintf("%s-hatch-%d", id, index)
+
+				fmt.Fprintf(builder, `<defs><pattern id="%s" patternUnits="userSpaceOnUse" width="8" height="8">`+
+					`<rect width="8" height="8" fill="%s"/><path d="M0,8 L8,0" stroke="%s" stroke-width="1"/></pattern></defs>`+"\n",
+					patternID, fill, sectionColor(foreground, sectionDefaultForeground))
+
+				fill = "url(#" + patternID + ")"
+			}
+
+			fmt.Fprintf(builder, `<rect x="%g" y="%g" width="%g" height="%g" fill="%s" stroke="#000000" stroke-width="0.5"/>`+"\n",
+				offset, top, width, sectionHeight, fill)
+		}
+
+		fmt.Fprintf(builder, `<text transform="translate(%g,%g) rotate(90)" font-family="sans-serif" font-size="10" dominant-baseline="middle">%s</text>`+"\n",
+			center, top+sectionHeight+6, html.EscapeString(sectionLayerLabel(layer)))
+
+		offset += width
+	}
+
+	builder.WriteString("</g>\n")
+}
+
+func writeSVG(dst io.Writer, width, height float64, body string) error {
+	_, err := fmt.Fprintf(dst, `<svg xmlns="http://www.w3.org/2000/svg" width="%g" height="%g" viewBox="0 0 %g %g">`+"\n%s</svg>\n",
+		width, height, width, height, body)
+
+	return err
+}
+
+func wallTypeSectionSVG(dst io.Writer, wallType *models.WallType) error {
+	var builder strings.Builder
+
+	writeWallTypeSection(&builder, wallType, "section", 0, 0)
+
+	return writeSVG(dst, sectionWidth(wallType), sectionTitleHeight+sectionHeight+sectionLabelsHeight, builder.String())
+}
+
+func (s *Spreadsheet) findWallType(ctx context.Context, name string) (*models.WallType, error) {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	for _, wallType := range wallTypes {
+		if wallType.Name == name {
+			return wallType, nil
+		}
+	}
+
+	return nil, errors.Wrapf(models.ErrNotFound, "wall type %s", name)
+}
+
+func (s *Spreadsheet) ReadWallTypeSectionTo(ctx context.Context, name string, dst io.Writer) error {
+	wallType, err := s.findWallType(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if err := wallTypeSectionSVG(dst, wallType); err != nil {
+		return errors.Wrap(err, "Unable to write wall type section")
+	}
+
+	return nil
+}
+
+// ReadWallTypesSectionsTo writes a contact sheet with the sections of all
+// the wall types stacked in a single SVG.
+func (s *Spreadsheet) ReadWallTypesSectionsTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	var (
+		builder       strings.Builder
+		width, height float64
+	)
+
+	for index, wallType := range wallTypes {
+		writeWallTypeSection(&builder, wallType, fmt.Sprintf("section-%d", index), 0, height)
+
+		height += sectionTitleHeight + sectionHeight + sectionLabelsHeight
+		if sectionWidth(wallType) > width {
+			width = sectionWidth(wallType)
+		}
+	}
+
+	if err := writeSVG(dst, width, height, builder.String()); err != nil {
+		return errors.Wrap(err, "Unable to write wall types sections")
+	}
+
+	return nil
+}
+
+// ReadWallTypesSectionsHTMLTo writes the contact sheet as an HTML page with
+// one inline SVG per wall type.
+func (s *Spreadsheet) ReadWallTypesSectionsHTMLTo(ctx context.Context, dst io.Writer) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>Wall types</title>\n</head>\n<body>\n")
+
+	for index, wallType := range wallTypes {
+		var section strings.Builder
+
+		writeWallTypeSection(&section, wallType, fmt.Sprintf("section-%d", index), 0, 0)
+
+		fmt.Fprintf(&builder, "<figure id=\"%s\">\n", html.EscapeString(wallType.Fingerprint))
+
+		if err := writeSVG(&builder, sectionWidth(wallType), sectionTitleHeight+sectionHeight+sectionLabelsHeight, section.String()); err != nil {
+			return errors.Wrap(err, "Unable to write wall types sections")
+		}
+
+		fmt.Fprintf(&builder, "<figcaption>%s</figcaption>\n</figure>\n", html.EscapeString(wallType.Name))
+	}
+
+	builder.WriteString("</body>\n</html>\n")
+
+	if _, err := io.WriteString(dst, builder.String()); err != nil {
+		return errors.Wrap(err, "Unable to write wall types sections")
+	}
+
+	return nil
+}
@@ -2,38 +2,101 @@ package spreadsheet
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 
+	"arca3/config"
 	"arca3/models"
 )
 
 const (
 	effectiveValue = "sheets/data/rowData/values/effectiveValue"
+
+	materialsSheetID      = 1466546092
+	areasSheetID          = 2055988922
+	areasRelationsSheetID = 1715124245
 )
 
 type Spreadsheet struct {
 	client        *sheets.Service
 	spreadsheetID string
 
+	wallTypeNameTemplate string
+	thermalTargetUValue  float64
+	currencyRates        map[string]float64
+	costCurrency         string
+	thicknessUnit        models.LengthUnit
+
 	materials      models.WallMaterials
 	areas          models.Areas
 	areasMaterials models.AreasMaterials
 	relations      models.AreasRelations
+	requirements   models.AreasRequirements
+	patterns       models.FillPatterns
+
+	materialsUnit models.LengthUnit
+	invalidColors models.InvalidColors
+	materialsRows sheetRows
+	areasRows     sheetRows
+	relationsRows sheetRows
+}
+
+// sheetRows maps the key of every record read from a tab to its 0-based row
+// in the sheet, next being the row right after the records.
+type sheetRows struct {
+	indexes map[string]int64
+	next    int64
+}
+
+func newSheetRows() sheetRows {
+	return sheetRows{indexes: map[string]int64{}, next: 1}
 }
 
-func New(ctx context.Context, credentialsPath, spreadsheetID string) *Spreadsheet {
-	client, err := sheets.NewService(ctx, option.WithCredentialsFile(credentialsPath))
+// add records the data row at index, the first record wins over duplicates.
+func (r *sheetRows) add(key string, index int) {
+	if _, ok := r.indexes[key]; !ok {
+		r.indexes[key] = int64(index) + 1
+	}
+
+	r.next = int64(index) + 2
+}
+
+func New(ctx context.Context, env *config.Config) *Spreadsheet {
+	client, err := sheets.NewService(ctx, option.WithCredentialsFile(env.ServiceCredentialsPath))
 	if err != nil {
 		log.Fatalf("Unable to create Sheets service: %v", err)
 	}
 
+	currencyRates, err := env.Rates()
+	if err != nil {
+		log.Fatalf("Unable to read currency rates: %v", err)
+	}
+
+	if _, ok := currencyRates[strings.ToUpper(env.CostCurrency)]; !ok {
+		log.Fatalf("Unable to find a rate for COST_CURRENCY %s in CURRENCY_RATES", env.CostCurrency)
+	}
+
+	thicknessUnit, err := models.ParseLengthUnit(env.ThicknessUnit)
+	if err != nil {
+		log.Fatalf("Unable to read thickness unit: %v", err)
+	}
+
 	return &Spreadsheet{
 		client:        client,
-		spreadsheetID: spreadsheetID,
+		spreadsheetID: env.SpreadsheetID,
+
+		wallTypeNameTemplate: env.WallTypeNameTemplate,
+		thermalTargetUValue:  env.ThermalTargetUValue,
+		currencyRates:        currencyRates,
+		costCurrency:         strings.ToUpper(env.CostCurrency),
+		thicknessUnit:        thicknessUnit,
 	}
 }
 
@@ -42,6 +105,12 @@ func (s *Spreadsheet) ResetData() {
 	s.areas = nil
 	s.areasMaterials = nil
 	s.relations = nil
+	s.requirements = nil
+	s.patterns = nil
+	s.invalidColors = nil
+	s.materialsRows = sheetRows{}
+	s.areasRows = sheetRows{}
+	s.relationsRows = sheetRows{}
 }
 
 func readPtrStringByCellIndex(row *sheets.RowData, index int) *string {
@@ -66,6 +135,58 @@ func readPtrStringByCellIndex(row *sheets.RowData, index int) *string {
 	return value
 }
 
+func readPtrNumberByCellIndex(row *sheets.RowData, index int) *float64 {
+	if len(row.Values) <= index {
+		return nil
+	}
+
+	if row.Values[index] == nil {
+		return nil
+	}
+
+	if row.Values[index].EffectiveValue == nil {
+		return nil
+	}
+
+	return row.Values[index].EffectiveValue.NumberValue
+}
+
+// readPtrTextByCellIndex reads a cell that may hold either text or a number,
+// like a level typed as 1 or as "L1".
+func readPtrTextByCellIndex(row *sheets.RowData, index int) *string {
+	if value := readPtrStringByCellIndex(row, index); value != nil {
+		return value
+	}
+
+	if number := readPtrNumberByCellIndex(row, index); number != nil {
+		value := strconv.FormatFloat(*number, 'f', -1, 64)
+
+		return &value
+	}
+
+	return nil
+}
+
+// readPtrColorByCellIndex parses the color of a cell, empty cells give nil.
+// Sheets turns hex colors made of digits only, like 000000 or 123456, into
+// numbers, those are read back as six hex digits.
+func readPtrColorByCellIndex(row *sheets.RowData, index int) (*models.Color, error) {
+	if number := readPtrNumberByCellIndex(row, index); number != nil {
+		if *number < 0 || *number > 999999 || *number != math.Trunc(*number) {
+			return nil, errors.Wrapf(models.ErrInvalid, "unknown color %v", *number)
+		}
+
+		return models.ParseColor(fmt.Sprintf("%06d", int(*number)))
+	}
+
+	value := readPtrStringByCellIndex(row, index)
+	if value == nil || strings.TrimSpace(*value) == "" {
+		return nil, nil
+	}
+
+	return models.ParseColor(*value)
+}
+
 func readStringByCellIndex(row *sheets.RowData, index int) (string, error) {
 	if len(row.Values) <= index {
 		return "", errors.Wrapf(models.ErrInvalid, "index %d out of range for row with %d values", index, len(row.Values))
@@ -0,0 +1,60 @@
+package spreadsheet
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+func TestReadPtrColorByCellIndex(t *testing.T) {
+	tests := []struct {
+		name  string
+		value *sheets.ExtendedValue
+		want  string
+	}{
+		{"empty", nil, ""},
+		{"hex", &sheets.ExtendedValue{StringValue: stringPtr("#FF0000")}, "#FF0000"},
+		{"name", &sheets.ExtendedValue{StringValue: stringPtr("navy")}, "#000080"},
+		{"zeros typed as a number", &sheets.ExtendedValue{NumberValue: floatPtr(0)}, "#000000"},
+		{"digits typed as a number", &sheets.ExtendedValue{NumberValue: floatPtr(123456)}, "#123456"},
+		{"leading zeros lost", &sheets.ExtendedValue{NumberValue: floatPtr(80)}, "#000080"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			row := &sheets.RowData{Values: []*sheets.CellData{{EffectiveValue: test.value}}}
+
+			color, err := readPtrColorByCellIndex(row, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := ""
+			if color != nil {
+				got = color.Hex()
+			}
+
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestReadPtrColorByCellIndexInvalid(t *testing.T) {
+	for _, value := range []*sheets.ExtendedValue{
+		{StringValue: stringPtr("not a color")},
+		{NumberValue: floatPtr(1234567)},
+		{NumberValue: floatPtr(12.5)},
+		{NumberValue: floatPtr(-1)},
+	} {
+		row := &sheets.RowData{Values: []*sheets.CellData{{EffectiveValue: value}}}
+
+		if _, err := readPtrColorByCellIndex(row, 0); !errors.Is(err, models.ErrInvalid) {
+			t.Errorf("got %v for %+v, want an ErrInvalid", err, value)
+		}
+	}
+}
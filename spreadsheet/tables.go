@@ -0,0 +1,115 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/xuri/excelize/v2"
+
+	"arca3/models"
+)
+
+// writeTable writes rows, the first one being the header, as CSV or as a
+// single sheet XLSX workbook. Cells are strings, numbers, booleans or nil.
+func writeTable(dst io.Writer, format models.Format, sheet string, rows [][]interface{}) error {
+	switch format {
+	case models.FormatCSV:
+		return writeTableCSV(dst, rows)
+	case models.FormatXLSX:
+		return writeTableXLSX(dst, sheet, rows)
+	default:
+		return errors.Wrapf(models.ErrInvalid, "format %s is not a table format", format)
+	}
+}
+
+func writeTableCSV(dst io.Writer, rows [][]interface{}) error {
+	writer := csv.NewWriter(dst)
+
+	for _, row := range rows {
+		record := make([]string, 0, len(row))
+		for _, cell := range row {
+			record = append(record, formatTableCell(cell))
+		}
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func formatTableCell(cell interface{}) string {
+	switch value := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(value)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		return ""
+	}
+}
+
+func writeTableXLSX(dst io.Writer, sheet string, rows [][]interface{}) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	if err := file.SetSheetName(file.GetSheetName(0), sheet); err != nil {
+		return err
+	}
+
+	for index, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, index+1)
+		if err != nil {
+			return err
+		}
+
+		if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+
+	return file.Write(dst)
+}
+
+// readTable reads the rows of a CSV body or of the first sheet of an XLSX
+// workbook, the first row being the header.
+func readTable(src io.Reader, format models.Format) ([][]string, error) {
+	switch format {
+	case models.FormatCSV:
+		reader := csv.NewReader(src)
+		reader.FieldsPerRecord = -1
+
+		rows, err := reader.ReadAll()
+		if err != nil {
+			return nil, errors.Wrap(models.ErrInvalid, "Unable to read CSV: "+err.Error())
+		}
+
+		return rows, nil
+	case models.FormatXLSX:
+		file, err := excelize.OpenReader(src)
+		if err != nil {
+			return nil, errors.Wrap(models.ErrInvalid, "Unable to read XLSX: "+err.Error())
+		}
+		defer file.Close()
+
+		rows, err := file.GetRows(file.GetSheetName(0))
+		if err != nil {
+			return nil, errors.Wrap(models.ErrInvalid, "Unable to read XLSX: "+err.Error())
+		}
+
+		return rows, nil
+	default:
+		return nil, errors.Wrapf(models.ErrInvalid, "format %s is not a table format", format)
+	}
+}
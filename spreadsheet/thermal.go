@@ -0,0 +1,96 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// Surface resistances for horizontal heat flow from ISO 6946, in m²K/W.
+const (
+	surfaceResistanceInterior = 0.13
+	surfaceResistanceExterior = 0.04
+)
+
+// computeWallThermal sums the layer resistances of an assembly. Thickness is
+// in millimeters and conductivity in W/(m·K); a layer other than a membrane
+// without conductivity leaves the result incomplete, and an incomplete
+// assembly is never compliant.
+func computeWallThermal(assembly *models.WallAssembly, targetU float64) *models.WallThermal {
+	thermal := &models.WallThermal{
+		WallType: assembly.WallType,
+		Relation: assembly.Relation,
+		Rsi:      surfaceResistanceInterior,
+		Rse:      surfaceResistanceInterior,
+		TargetU:  targetU,
+		Complete: true,
+	}
+
+	if assembly.Exterior {
+		thermal.Rse = surfaceResistanceExterior
+	}
+
+	thermal.RValue = thermal.Rsi + thermal.Rse
+
+	for _, layer := range assembly.Layers {
+		layerThermal := &models.WallLayerThermal{
+			Thickness: layer.Thickness,
+		}
+
+		if layer.Material != nil {
+			layerThermal.Material = layer.Material.Name
+			layerThermal.Conductivity = layer.Material.Conductivity
+		}
+
+		thermal.Layers = append(thermal.Layers, layerThermal)
+
+		if layer.Thickness == 0 {
+			continue
+		}
+
+		if layerThermal.Conductivity == nil || *layerThermal.Conductivity <= 0 {
+			thermal.Complete = false
+
+			continue
+		}
+
+		rValue := layer.Thickness / 1000 / *layerThermal.Conductivity
+		layerThermal.RValue = &rValue
+		thermal.RValue += rValue
+	}
+
+	uValue := 1 / thermal.RValue
+	thermal.UValue = &uValue
+	thermal.Compliant = thermal.Complete && uValue <= targetU
+
+	return thermal
+}
+
+// ReadWallTypesThermalTo reports the thermal performance of every assembly,
+// targetU overrides the configured target U-value when it's not nil.
+func (s *Spreadsheet) ReadWallTypesThermalTo(ctx context.Context, dst io.Writer, targetU *float64) error {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	target := s.thermalTargetUValue
+	if targetU != nil {
+		target = *targetU
+	}
+
+	thermals := make(models.WallsThermal, 0, len(assemblies))
+	for _, assembly := range assemblies {
+		thermals = append(thermals, computeWallThermal(assembly, target))
+	}
+
+	if err := json.NewEncoder(dst).Encode(thermals); err != nil {
+		return errors.Wrap(err, "Unable to encode wall thermal performance to JSON")
+	}
+
+	return nil
+}
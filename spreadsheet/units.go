@@ -0,0 +1,119 @@
+package spreadsheet
+
+import (
+	"arca3/models"
+)
+
+// The convert functions copy what they are given with thicknesses in unit,
+// the cached data stays in millimeters.
+
+func convertWallMaterial(material *models.WallMaterial, unit models.LengthUnit) *models.WallMaterial {
+	if material == nil {
+		return nil
+	}
+
+	converted := *material
+	converted.Thickness = unit.FromMillimeters(material.Thickness)
+
+	return &converted
+}
+
+func convertWallMaterials(materials models.WallMaterials, unit models.LengthUnit) models.WallMaterials {
+	if unit == "" || unit == models.UnitMillimeter || materials == nil {
+		return materials
+	}
+
+	converted := make(models.WallMaterials, 0, len(materials))
+	for _, material := range materials {
+		converted = append(converted, convertWallMaterial(material, unit))
+	}
+
+	return converted
+}
+
+func convertAreasMaterials(areasMaterials models.AreasMaterials, unit models.LengthUnit) models.AreasMaterials {
+	if unit == "" || unit == models.UnitMillimeter || areasMaterials == nil {
+		return areasMaterials
+	}
+
+	converted := make(models.AreasMaterials, 0, len(areasMaterials))
+	for _, areaMaterials := range areasMaterials {
+		converted = append(converted, &models.AreaMaterials{
+			Area:      areaMaterials.Area,
+			Materials: convertWallMaterials(areaMaterials.Materials, unit),
+		})
+	}
+
+	return converted
+}
+
+func convertAreasRelations(relations models.AreasRelations, unit models.LengthUnit) models.AreasRelations {
+	if unit == "" || unit == models.UnitMillimeter || relations == nil {
+		return relations
+	}
+
+	converted := make(models.AreasRelations, 0, len(relations))
+	for _, relation := range relations {
+		copied := *relation
+		copied.Central = convertWallMaterial(relation.Central, unit)
+
+		converted = append(converted, &copied)
+	}
+
+	return converted
+}
+
+func convertWallLayers(layers models.WallLayers, unit models.LengthUnit) models.WallLayers {
+	if layers == nil {
+		return nil
+	}
+
+	converted := make(models.WallLayers, 0, len(layers))
+	for _, layer := range layers {
+		copied := *layer
+		copied.Thickness = unit.FromMillimeters(layer.Thickness)
+
+		converted = append(converted, &copied)
+	}
+
+	return converted
+}
+
+func convertWallTypes(wallTypes models.WallTypes, unit models.LengthUnit) models.WallTypes {
+	if unit == "" || unit == models.UnitMillimeter || wallTypes == nil {
+		return wallTypes
+	}
+
+	converted := make(models.WallTypes, 0, len(wallTypes))
+	for _, wallType := range wallTypes {
+		copied := *wallType
+		copied.TotalThickness = unit.FromMillimeters(wallType.TotalThickness)
+		copied.Layers = convertWallLayers(wallType.Layers, unit)
+		copied.Relations = convertAreasRelations(wallType.Relations, unit)
+
+		converted = append(converted, &copied)
+	}
+
+	return converted
+}
+
+func convertWallAssemblies(assemblies models.WallAssemblies, unit models.LengthUnit) models.WallAssemblies {
+	if unit == "" || unit == models.UnitMillimeter || assemblies == nil {
+		return assemblies
+	}
+
+	converted := make(models.WallAssemblies, 0, len(assemblies))
+	for _, assembly := range assemblies {
+		copied := *assembly
+		copied.TotalThickness = unit.FromMillimeters(assembly.TotalThickness)
+		copied.Layers = convertWallLayers(assembly.Layers, unit)
+
+		if assembly.Relation != nil {
+			copied.Relation = convertAreasRelations(models.AreasRelations{assembly.Relation}, unit)[0]
+		}
+
+		converted = append(converted, &copied)
+	}
+
+	return converted
+}
@@ -0,0 +1,233 @@
+package spreadsheet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// wallRule checks one of the constraints Revit enforces on compound
+// structures. Layer numbers in the violations are 1-based and count from the
+// exterior finish, 0 means the violation concerns the whole assembly.
+type wallRule struct {
+	name  string
+	check func(assembly *models.WallAssembly) []*models.WallRuleViolation
+}
+
+var wallRules = []wallRule{
+	{name: "core-missing", check: checkCoreMissing},
+	{name: "layer-thickness", check: checkLayerThickness},
+	{name: "membrane-thickness", check: checkMembraneThickness},
+	{name: "structure-in-core", check: checkStructureInCore},
+	{name: "finish-outside-core", check: checkFinishOutsideCore},
+	{name: "single-structural-material", check: checkSingleStructuralMaterial},
+	{name: "function-priority", check: checkFunctionPriority},
+}
+
+func checkWallAssembly(assembly *models.WallAssembly) models.WallRuleViolations {
+	violations := models.WallRuleViolations{}
+
+	for _, rule := range wallRules {
+		for _, violation := range rule.check(assembly) {
+			violation.WallType = assembly.WallType
+			violation.Relation = assembly.Relation
+			violation.Rule = rule.name
+			violations = append(violations, violation)
+		}
+	}
+
+	return violations
+}
+
+func checkCoreMissing(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	if len(assembly.Layers) == 0 {
+		return []*models.WallRuleViolation{{Message: "assembly has no layers"}}
+	}
+
+	if _, _, ok := coreBoundaries(assembly.Layers); !ok {
+		return []*models.WallRuleViolation{{Message: "assembly has no core layer"}}
+	}
+
+	return nil
+}
+
+func checkLayerThickness(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	violations := []*models.WallRuleViolation{}
+
+	for index, layer := range assembly.Layers {
+		if layer.Function != models.FunctionMembrane && layer.Thickness <= 0 {
+			violations = append(violations, &models.WallRuleViolation{
+				Layer:   index + 1,
+				Message: fmt.Sprintf("%s layer %s must have a positive thickness, got %g", layer.Function, layerMaterialName(layer), layer.Thickness),
+			})
+		}
+	}
+
+	return violations
+}
+
+func checkMembraneThickness(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	violations := []*models.WallRuleViolation{}
+
+	for index, layer := range assembly.Layers {
+		if layer.Function == models.FunctionMembrane && layer.Thickness != 0 {
+			violations = append(violations, &models.WallRuleViolation{
+				Layer:   index + 1,
+				Message: fmt.Sprintf("membrane layer %s must have zero thickness, got %g", layerMaterialName(layer), layer.Thickness),
+			})
+		}
+	}
+
+	return violations
+}
+
+func checkStructureInCore(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	violations := []*models.WallRuleViolation{}
+
+	for index, layer := range assembly.Layers {
+		if layer.IsCore {
+			continue
+		}
+
+		if layer.Function == models.FunctionStructure || layer.IsStructural {
+			violations = append(violations, &models.WallRuleViolation{
+				Layer:   index + 1,
+				Message: fmt.Sprintf("structural layer %s must be inside the core boundaries", layerMaterialName(layer)),
+			})
+		}
+	}
+
+	return violations
+}
+
+func checkFinishOutsideCore(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	violations := []*models.WallRuleViolation{}
+
+	for index, layer := range assembly.Layers {
+		if !layer.IsCore {
+			continue
+		}
+
+		if layer.Function == models.FunctionFinish1 || layer.Function == models.FunctionFinish2 {
+			violations = append(violations, &models.WallRuleViolation{
+				Layer:   index + 1,
+				Message: fmt.Sprintf("finish layer %s must be outside the core boundaries", layerMaterialName(layer)),
+			})
+		}
+	}
+
+	return violations
+}
+
+func checkSingleStructuralMaterial(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	count := 0
+	for _, layer := range assembly.Layers {
+		if layer.IsStructural {
+			count++
+		}
+	}
+
+	if count > 1 {
+		return []*models.WallRuleViolation{{Message: fmt.Sprintf("only one layer can be the structural material, got %d", count)}}
+	}
+
+	return nil
+}
+
+// checkFunctionPriority walks from the core towards each face, a layer can't
+// have a higher priority (lower function value) than the layers between it
+// and the core. Membranes have no priority and are skipped.
+func checkFunctionPriority(assembly *models.WallAssembly) []*models.WallRuleViolation {
+	first, last, ok := coreBoundaries(assembly.Layers)
+	if !ok {
+		return nil
+	}
+
+	violations := []*models.WallRuleViolation{}
+
+	walk := func(indexes []int) {
+		previous := models.FunctionNone
+
+		for _, index := range indexes {
+			layer := assembly.Layers[index]
+			if layer.Function == models.FunctionMembrane {
+				continue
+			}
+
+			if layer.Function < previous {
+				violations = append(violations, &models.WallRuleViolation{
+					Layer:   index + 1,
+					Message: fmt.Sprintf("%s layer %s can't be further from the core than a %s layer", layer.Function, layerMaterialName(layer), previous),
+				})
+
+				continue
+			}
+
+			previous = layer.Function
+		}
+	}
+
+	exterior := []int{}
+	for index := first - 1; index >= 0; index-- {
+		exterior = append(exterior, index)
+	}
+
+	interior := []int{}
+	for index := last + 1; index < len(assembly.Layers); index++ {
+		interior = append(interior, index)
+	}
+
+	walk(exterior)
+	walk(interior)
+
+	return violations
+}
+
+func coreBoundaries(layers models.WallLayers) (int, int, bool) {
+	first, last := -1, -1
+
+	for index, layer := range layers {
+		if !layer.IsCore {
+			continue
+		}
+
+		if first == -1 {
+			first = index
+		}
+
+		last = index
+	}
+
+	return first, last, first != -1
+}
+
+func layerMaterialName(layer *models.WallLayer) string {
+	if layer.Material == nil || layer.Material.Name == nil {
+		return "(unnamed)"
+	}
+
+	return *layer.Material.Name
+}
+
+func (s *Spreadsheet) ReadWallTypesViolationsTo(ctx context.Context, dst io.Writer) error {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	violations := models.WallRuleViolations{}
+	for _, assembly := range assemblies {
+		violations = append(violations, checkWallAssembly(assembly)...)
+	}
+
+	if err := json.NewEncoder(dst).Encode(violations); err != nil {
+		return errors.Wrap(err, "Unable to encode wall rule violations to JSON")
+	}
+
+	return nil
+}
@@ -0,0 +1,101 @@
+package spreadsheet
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	"google.golang.org/api/sheets/v4"
+
+	"arca3/models"
+)
+
+const (
+	wallTypesRange = "WALL_TYPES!A:G"
+)
+
+// WriteWallTypes recomputes the wall types and replaces the whole content of
+// the WALL_TYPES tab with them, one row per layer.
+func (s *Spreadsheet) WriteWallTypes(ctx context.Context) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	values := [][]interface{}{
+		{"Name", "TotalThickness", "Layer", "Function", "Thickness", "Material", "Relations"},
+	}
+
+	for _, wallType := range wallTypes {
+		relations := make([]string, 0, len(wallType.Relations))
+		for _, relation := range wallType.Relations {
+			relations = append(relations, relationLabel(relation))
+		}
+
+		if len(wallType.Layers) == 0 {
+			values = append(values, []interface{}{
+				wallType.Name, wallType.TotalThickness, "", "", "", "", strings.Join(relations, "\n"),
+			})
+
+			continue
+		}
+
+		for index, layer := range wallType.Layers {
+			material := ""
+			if layer.Material != nil && layer.Material.Name != nil {
+				material = *layer.Material.Name
+			}
+
+			relationsCell := ""
+			if index == 0 {
+				relationsCell = strings.Join(relations, "\n")
+			}
+
+			values = append(values, []interface{}{
+				wallType.Name, wallType.TotalThickness, index + 1, layer.Function.String(), layer.Thickness, material, relationsCell,
+			})
+		}
+	}
+
+	if _, err := s.client.Spreadsheets.Values.Clear(
+		s.spreadsheetID,
+		wallTypesRange,
+		&sheets.ClearValuesRequest{}).
+		Context(ctx).
+		Do(); err != nil {
+		return errors.Wrapf(err, "Unable to clear spreadsheet %s", wallTypesRange)
+	}
+
+	if _, err := s.client.Spreadsheets.Values.Update(
+		s.spreadsheetID,
+		wallTypesRange,
+		&sheets.ValueRange{Values: values}).
+		ValueInputOption("RAW").
+		Context(ctx).
+		Do(); err != nil {
+		return errors.Wrapf(err, "Unable to update spreadsheet %s", wallTypesRange)
+	}
+
+	return nil
+}
+
+func relationLabel(relation *models.AreaRelation) string {
+	var (
+		internal, external string
+	)
+
+	if relation.AreaInternal != nil {
+		internal = relation.AreaInternal.Name
+	}
+
+	switch {
+	case relation.SameArea:
+		external = internal
+	case relation.AreaExternal != nil:
+		external = relation.AreaExternal.Name
+	default:
+		external = "(none)"
+	}
+
+	return internal + " → " + external
+}
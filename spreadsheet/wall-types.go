@@ -0,0 +1,235 @@
+package spreadsheet
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"arca3/models"
+)
+
+// getWallAssemblies builds one assembly per area relation. AREAS_MATERIALS
+// lists the layers of an area from the core outwards to the finish seen from
+// that area, so the external area is mirrored to get the exterior finish
+// first and the internal area is kept as is to end with the interior finish.
+// An assembly is exterior when its external side is an exterior or ground
+// area, or when there's no external area at all.
+func (s *Spreadsheet) getWallAssemblies(ctx context.Context) (models.WallAssemblies, error) {
+	if s.areasMaterials == nil {
+		if err := s.getAreasMaterials(ctx); err != nil {
+			return nil, errors.Wrap(err, "Unable to get areas materials")
+		}
+	}
+
+	if s.relations == nil {
+		if err := s.getAreasRelations(ctx); err != nil {
+			return nil, errors.Wrap(err, "Unable to get areas relations")
+		}
+	}
+
+	assemblies := make(models.WallAssemblies, 0, len(s.relations))
+	for _, relation := range s.relations {
+		assemblies = append(assemblies, s.buildWallAssembly(relation))
+	}
+
+	return assemblies, nil
+}
+
+func (s *Spreadsheet) buildWallAssembly(relation *models.AreaRelation) *models.WallAssembly {
+	areaExternal := relation.AreaExternal
+	if relation.SameArea {
+		areaExternal = relation.AreaInternal
+	}
+
+	assembly := &models.WallAssembly{
+		Relation: relation,
+		Exterior: areaExternal == nil || areaExternal.IsExterior(),
+	}
+
+	if areaExternal != nil {
+		external := s.findAreaMaterials(areaExternal.Name)
+		for index := len(external) - 1; index >= 0; index-- {
+			assembly.Layers = appendWallLayer(assembly.Layers, external[index], areaExternal, false)
+		}
+	}
+
+	if relation.Central != nil {
+		assembly.Layers = appendWallLayer(assembly.Layers, relation.Central, nil, true)
+	}
+
+	if relation.AreaInternal != nil {
+		for _, material := range s.findAreaMaterials(relation.AreaInternal.Name) {
+			assembly.Layers = appendWallLayer(assembly.Layers, material, relation.AreaInternal, false)
+		}
+	}
+
+	for _, layer := range assembly.Layers {
+		assembly.TotalThickness += layer.Thickness
+	}
+
+	return assembly
+}
+
+func appendWallLayer(layers models.WallLayers, material *models.WallMaterial, area *models.Area, isCore bool) models.WallLayers {
+	if material == nil {
+		return layers
+	}
+
+	return append(layers, &models.WallLayer{
+		Thickness:    material.Thickness,
+		Function:     material.Function,
+		IsStructural: material.IsStructural,
+		IsCore:       isCore,
+		Area:         area,
+		Material:     material.Material,
+	})
+}
+
+func (s *Spreadsheet) findAreaMaterials(name string) models.WallMaterials {
+	for _, areaMaterials := range s.areasMaterials {
+		if areaMaterials.Area != nil && areaMaterials.Area.Name == name {
+			return areaMaterials.Materials
+		}
+	}
+
+	return nil
+}
+
+// getWallTypes groups the relations whose assemblies share the same layer
+// sequence into a single wall type, named after wallTypeNameTemplate. Both
+// the wall types and the assemblies come out in relation order, so the names
+// stay the same between requests as long as the spreadsheet doesn't change.
+func (s *Spreadsheet) getWallTypes(ctx context.Context) (models.WallTypes, models.WallAssemblies, error) {
+	assemblies, err := s.getWallAssemblies(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	wallTypes := models.WallTypes{}
+	wallTypesByFingerprint := map[string]*models.WallType{}
+
+	for _, assembly := range assemblies {
+		fingerprint := fingerprintWallLayers(assembly.Layers)
+
+		wallType, ok := wallTypesByFingerprint[fingerprint]
+		if !ok {
+			wallType = &models.WallType{
+				Fingerprint:    fingerprint,
+				TotalThickness: assembly.TotalThickness,
+				Layers:         assembly.Layers,
+			}
+			wallTypesByFingerprint[fingerprint] = wallType
+			wallTypes = append(wallTypes, wallType)
+		}
+
+		wallType.Relations = append(wallType.Relations, assembly.Relation)
+	}
+
+	names := map[string]int{}
+	for _, wallType := range wallTypes {
+		name := nameWallType(s.wallTypeNameTemplate, wallType)
+
+		names[name]++
+		if names[name] > 1 {
+			name = fmt.Sprintf("%s (%d)", name, names[name])
+		}
+
+		wallType.Name = name
+	}
+
+	for _, assembly := range assemblies {
+		assembly.WallType = wallTypesByFingerprint[fingerprintWallLayers(assembly.Layers)].Name
+	}
+
+	return wallTypes, assemblies, nil
+}
+
+func fingerprintWallLayers(layers models.WallLayers) string {
+	hash := sha1.New()
+
+	for _, layer := range layers {
+		name := ""
+		if layer.Material != nil && layer.Material.Name != nil {
+			name = *layer.Material.Name
+		}
+
+		fmt.Fprintf(hash, "%s|%g|%s|%t|%t\n", name, layer.Thickness, layer.Function, layer.IsStructural, layer.IsCore)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
+
+func nameWallType(template string, wallType *models.WallType) string {
+	var (
+		coreMaterial, keynote string
+	)
+
+	for _, layer := range wallType.Layers {
+		if !layer.IsCore || layer.Material == nil {
+			continue
+		}
+
+		if layer.Material.Name != nil {
+			coreMaterial = *layer.Material.Name
+		}
+
+		if layer.Material.Keynote != nil {
+			keynote = *layer.Material.Keynote
+		}
+	}
+
+	for _, relation := range wallType.Relations {
+		if relation.WallKeynote != nil && *relation.WallKeynote != "" {
+			keynote = *relation.WallKeynote
+
+			break
+		}
+	}
+
+	name := strings.NewReplacer(
+		"{totalThickness}", strconv.FormatFloat(wallType.TotalThickness, 'f', -1, 64),
+		"{coreMaterial}", coreMaterial,
+		"{keynote}", keynote,
+		"{layers}", strconv.Itoa(len(wallType.Layers)),
+		"{fingerprint}", wallType.Fingerprint,
+	).Replace(template)
+
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		name = wallType.Fingerprint
+	}
+
+	return name
+}
+
+func (s *Spreadsheet) ReadWallTypesTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error {
+	wallTypes, _, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall types")
+	}
+
+	if err := writeData(dst, options.Format, "Wall types", convertWallTypes(wallTypes, options.Units)); err != nil {
+		return errors.Wrap(err, "Unable to encode wall types")
+	}
+
+	return nil
+}
+
+func (s *Spreadsheet) ReadWallAssembliesTo(ctx context.Context, dst io.Writer, options models.ReadOptions) error {
+	_, assemblies, err := s.getWallTypes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Unable to compute wall assemblies")
+	}
+
+	if err := writeData(dst, options.Format, "Wall assemblies", convertWallAssemblies(assemblies, options.Units)); err != nil {
+		return errors.Wrap(err, "Unable to encode wall assemblies")
+	}
+
+	return nil
+}